@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bronystylecrazy/gx/idcodec"
+)
+
+// fileConfig is the JSON config file layout; every field can also be set by
+// an env var and a flag, in increasing order of precedence.
+type fileConfig struct {
+	Secret      string `json:"secret"`
+	Version     *int   `json:"version"`
+	MacLen      *int   `json:"mac_len"`
+	Alphabet    string `json:"alphabet"`
+	Domain      string `json:"domain"`
+	Kind        string `json:"kind"`
+	Encoding    string `json:"encoding"`
+	Permutation string `json:"permutation"`
+	MinimalBody *bool  `json:"minimal_body"`
+	CheckChar   *bool  `json:"check_char"`
+	KDF         string `json:"kdf"`
+	Salt        string `json:"salt"`
+	Info        string `json:"info"`
+
+	AllowWeakSecret *bool `json:"allow_weak_secret"`
+}
+
+// configFlags registers the codec flags under prefix ("" or "from-") and
+// resolves them against env vars named IDCODEC_<PREFIX><FIELD>.
+type configFlags struct {
+	prefix string
+	file   *string
+	values map[string]*string
+}
+
+var configFields = []struct{ name, usage string }{
+	{"secret", "codec secret (raw, base64:... or hex:...)"},
+	{"secret-file", "read the codec secret from a file"},
+	{"version", "token version (0..radix-1)"},
+	{"mac-len", "MAC chars per token"},
+	{"alphabet", "alphabet override"},
+	{"domain", "domain separation string"},
+	{"kind", "default kind (single char)"},
+	{"encoding", "base62, base58 or crockford32"},
+	{"permutation", "legacy or speck"},
+	{"minimal-body", "drop leading zero digits (true/false)"},
+	{"check-char", "append a typo check char (true/false)"},
+	{"kdf", "legacy or hkdf"},
+	{"salt", "HKDF salt (raw, base64:... or hex:...)"},
+	{"info", "HKDF context string"},
+	{"allow-weak-secret", "accept secrets that fail idcodec.CheckSecret (true/false), for legacy deployments"},
+}
+
+func addConfigFlags(fs *flag.FlagSet, prefix string) *configFlags {
+	cf := &configFlags{prefix: prefix, values: map[string]*string{}}
+	cf.file = fs.String(prefix+"config", "", "JSON config file")
+	for _, f := range configFields {
+		cf.values[f.name] = fs.String(prefix+f.name, "", f.usage)
+	}
+	return cf
+}
+
+func (cf *configFlags) envName(field string) string {
+	name := strings.ToUpper(strings.ReplaceAll(cf.prefix+field, "-", "_"))
+	return "IDCODEC_" + name
+}
+
+// lookup returns the flag value, else the env var, else "".
+func (cf *configFlags) lookup(field string) string {
+	if v := *cf.values[field]; v != "" {
+		return v
+	}
+	return os.Getenv(cf.envName(field))
+}
+
+func (cf *configFlags) load() (idcodec.Config, error) {
+	var fc fileConfig
+	path := *cf.file
+	if path == "" {
+		path = os.Getenv(cf.envName("config"))
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return idcodec.Config{}, err
+		}
+		if err := json.Unmarshal(b, &fc); err != nil {
+			return idcodec.Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	cfg := idcodec.Config{Version: 0, MacLen: 8}
+	str := func(field, fromFile string) string {
+		if v := cf.lookup(field); v != "" {
+			return v
+		}
+		return fromFile
+	}
+	intField := func(field string, fromFile *int, dst *int) error {
+		if v := cf.lookup(field); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+			*dst = n
+		} else if fromFile != nil {
+			*dst = *fromFile
+		}
+		return nil
+	}
+	boolField := func(field string, fromFile *bool, dst *bool) error {
+		if v := cf.lookup(field); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+			*dst = b
+		} else if fromFile != nil {
+			*dst = *fromFile
+		}
+		return nil
+	}
+
+	var err error
+	if f := cf.lookup("secret-file"); f != "" {
+		cfg.Secret, err = idcodec.SecretFromFile(f)
+	} else {
+		cfg.Secret, err = idcodec.DecodeSecret(str("secret", fc.Secret))
+	}
+	if errors.Is(err, idcodec.ErrSecretMissing) {
+		return cfg, fmt.Errorf("missing secret (-%ssecret, -%ssecret-file, %s or config file)",
+			cf.prefix, cf.prefix, cf.envName("secret"))
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	version := int(cfg.Version)
+	if err := intField("version", fc.Version, &version); err != nil {
+		return cfg, err
+	}
+	if version < 0 || version > 255 {
+		return cfg, fmt.Errorf("version out of range: %d", version)
+	}
+	cfg.Version = uint8(version)
+	if err := intField("mac-len", fc.MacLen, &cfg.MacLen); err != nil {
+		return cfg, err
+	}
+	if err := boolField("minimal-body", fc.MinimalBody, &cfg.MinimalBody); err != nil {
+		return cfg, err
+	}
+	if err := boolField("check-char", fc.CheckChar, &cfg.CheckChar); err != nil {
+		return cfg, err
+	}
+	if err := boolField("allow-weak-secret", fc.AllowWeakSecret, &cfg.AllowWeakSecret); err != nil {
+		return cfg, err
+	}
+	cfg.Alphabet = str("alphabet", fc.Alphabet)
+	if d := str("domain", fc.Domain); d != "" {
+		cfg.Domain = []byte(d)
+	}
+	if k := str("kind", fc.Kind); k != "" {
+		if len(k) != 1 {
+			return cfg, fmt.Errorf("kind must be a single byte, got %q", k)
+		}
+		b := k[0]
+		cfg.Kind = &b
+	}
+
+	switch e := str("encoding", fc.Encoding); e {
+	case "", "base62":
+		cfg.Encoding = idcodec.EncodingBase62
+	case "base58":
+		cfg.Encoding = idcodec.EncodingBase58
+	case "crockford32":
+		cfg.Encoding = idcodec.EncodingCrockford32
+	default:
+		return cfg, fmt.Errorf("unknown encoding %q", e)
+	}
+	switch p := str("permutation", fc.Permutation); p {
+	case "", "legacy":
+		cfg.Permutation = idcodec.PermutationLegacy
+	case "speck":
+		cfg.Permutation = idcodec.PermutationSpeck
+	default:
+		return cfg, fmt.Errorf("unknown permutation %q", p)
+	}
+	switch k := str("kdf", fc.KDF); k {
+	case "", "legacy":
+		cfg.KDF = idcodec.KDFLegacy
+	case "hkdf":
+		cfg.KDF = idcodec.KDFHKDF
+	default:
+		return cfg, fmt.Errorf("unknown kdf %q", k)
+	}
+	if salt := str("salt", fc.Salt); salt != "" {
+		if cfg.Salt, err = idcodec.DecodeSecret(salt); err != nil {
+			return cfg, fmt.Errorf("salt: %w", err)
+		}
+	}
+	if info := str("info", fc.Info); info != "" {
+		cfg.Info = []byte(info)
+	}
+	return cfg, nil
+}
+
+func (cf *configFlags) codec() (*idcodec.Codec, idcodec.Config, error) {
+	cfg, err := cf.load()
+	if err != nil {
+		return nil, cfg, err
+	}
+	c, err := idcodec.NewCodecFromSecret(cfg)
+	return c, cfg, err
+}
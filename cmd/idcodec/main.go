@@ -1,35 +1,289 @@
+// Command idcodec encodes, decodes, validates, inspects and rotates idcodec
+// tokens from the shell.
+//
+//	idcodec encode [flags] [id ...]
+//	idcodec decode [flags] [token ...]
+//	idcodec validate [flags] [token ...]
+//	idcodec inspect [flags] [-kinds U,O] [token ...]
+//	idcodec rotate [flags] [-from-... flags] [token ...]
+//
+// With no positional args every subcommand reads one input per line from
+// stdin. The codec is configured by -config (JSON), IDCODEC_* env vars and
+// flags, later sources winning; rotate reads the old codec from the same set
+// prefixed with from-/IDCODEC_FROM_.
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 
-	"github.com/bronystylecrazy/gx"
 	"github.com/bronystylecrazy/gx/idcodec"
 )
 
-type User struct {
-	ID   gx.ID
-	Name string
-}
+const usage = `usage: idcodec <encode|decode|validate|inspect|rotate> [flags] [input ...]
+
+Inputs are read from stdin, one per line, when none are given.
+Run "idcodec <command> -h" for the flags of a command.
+`
 
 func main() {
-	// สร้าง codec แบบครบ config
-	codec := idcodec.MustNewCodecFromSecret(idcodec.Config{
-		Secret:  []byte("Sirawit Pratoomsuwan"),
-		Version: 1,
-		MacLen:  12,                             // ความยาว padding
-		Domain:  []byte("crane-scoop-platform"), // domain separation
-		Kind:    nil,                            // optional: default kind
-	})
-
-	// ตั้งเป็น default สำหรับ gx.ID
-	gx.SetDefaultCodec(codec)
-
-	// encode/decode ง่ายๆ
-	s := codec.EncodeUint64(1234567890)
-	fmt.Println("token:", s)
-	id, _ := codec.DecodeToUint64(s)
-	fmt.Println("decoded:", id)
-
-	fmt.Println(gx.NewID(1234567890))
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// result is one output line; Error is set for inputs that failed.
+type result struct {
+	Input   string `json:"input"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// inspection is the Details payload of the inspect command.
+type inspection struct {
+	Length      int      `json:"length"`
+	VersionChar string   `json:"version_char"`
+	VersionOK   bool     `json:"version_ok"`
+	Body        *uint64  `json:"body,omitempty"`
+	BodyError   string   `json:"body_error,omitempty"`
+	ID          *uint64  `json:"id,omitempty"`
+	MatchedKind *string  `json:"matched_kind,omitempty"`
+	TriedKinds  []string `json:"tried_kinds"`
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("idcodec "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print one JSON object per input")
+	cur := addConfigFlags(fs, "")
+	var from *configFlags
+	var kinds *string
+	switch cmd {
+	case "encode", "decode", "validate":
+	case "inspect":
+		kinds = fs.String("kinds", "", "comma-separated kinds to try when verifying the MAC")
+	case "rotate":
+		from = addConfigFlags(fs, "from-")
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "idcodec: unknown command %q\n%s", cmd, usage)
+		return 2
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	c, _, err := cur.codec()
+	if err != nil {
+		fmt.Fprintf(stderr, "idcodec: %v\n", err)
+		return 2
+	}
+
+	var handle func(in string) result
+	switch cmd {
+	case "encode":
+		handle = func(in string) result {
+			u, err := strconv.ParseUint(in, 10, 64)
+			if err != nil {
+				return result{Input: in, Error: err.Error()}
+			}
+			return result{Input: in, Output: c.EncodeUint64(u)}
+		}
+	case "decode":
+		handle = func(in string) result {
+			u, err := c.DecodeToUint64(in)
+			if err != nil {
+				return result{Input: in, Error: err.Error()}
+			}
+			return result{Input: in, Output: strconv.FormatUint(u, 10)}
+		}
+	case "validate":
+		handle = func(in string) result {
+			if err := c.Validate(in); err != nil {
+				return result{Input: in, Error: err.Error()}
+			}
+			return result{Input: in, Output: "ok"}
+		}
+	case "inspect":
+		try, err := parseKinds(*kinds)
+		if err != nil {
+			fmt.Fprintf(stderr, "idcodec: %v\n", err)
+			return 2
+		}
+		handle = func(in string) result { return inspect(c, try, in) }
+	case "rotate":
+		old, _, err := from.codec()
+		if err != nil {
+			fmt.Fprintf(stderr, "idcodec: from: %v\n", err)
+			return 2
+		}
+		var reg *idcodec.Registry
+		if old.Version() == c.Version() {
+			// Same version char: a registry cannot dispatch, so decode with
+			// the old codec explicitly.
+			reg, err = idcodec.NewRegistry(old)
+		} else {
+			reg, err = idcodec.NewRegistry(c, old)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "idcodec: rotate: %v\n", err)
+			return 2
+		}
+		handle = func(in string) result {
+			u, err := reg.DecodeToUint64(in)
+			if err != nil {
+				return result{Input: in, Error: err.Error()}
+			}
+			return result{Input: in, Output: c.EncodeUint64(u)}
+		}
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	enc := json.NewEncoder(out)
+	failed := false
+	emit := func(r result) {
+		if r.Error != "" {
+			failed = true
+		}
+		if *asJSON {
+			_ = enc.Encode(r)
+			return
+		}
+		switch {
+		case r.Details != nil:
+			printInspection(out, r)
+		case r.Error != "":
+			fmt.Fprintf(out, "%s\terror: %s\n", r.Input, r.Error)
+		default:
+			fmt.Fprintln(out, r.Output)
+		}
+	}
+
+	if fs.NArg() > 0 {
+		for _, in := range fs.Args() {
+			emit(handle(strings.TrimSpace(in)))
+		}
+	} else {
+		sc := bufio.NewScanner(stdin)
+		for sc.Scan() {
+			in := strings.TrimSpace(sc.Text())
+			if in == "" {
+				continue
+			}
+			emit(handle(in))
+		}
+		if err := sc.Err(); err != nil {
+			out.Flush()
+			fmt.Fprintf(stderr, "idcodec: read stdin: %v\n", err)
+			return 1
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// parseKinds splits "U,O" into kind pointers; the codec's own kind and no
+// kind are always tried as well.
+func parseKinds(s string) ([]*byte, error) {
+	var out []*byte
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if len(k) != 1 {
+			return nil, fmt.Errorf("kind must be a single byte, got %q", k)
+		}
+		b := k[0]
+		out = append(out, &b)
+	}
+	return out, nil
+}
+
+func kindName(k *byte) string {
+	if k == nil {
+		return "none"
+	}
+	return string(*k)
+}
+
+func inspect(c *idcodec.Codec, kinds []*byte, in string) result {
+	info := inspection{Length: len(in)}
+	r := result{Input: in, Details: &info}
+	if in == "" {
+		r.Error = idcodec.ErrInvalidLength.Error()
+		return r
+	}
+	info.VersionChar = in[:1]
+	info.VersionOK = c.HasVersion(in)
+	if body, err := c.DecodeBodyOnly(in); err != nil {
+		info.BodyError = err.Error()
+	} else {
+		info.Body = &body
+	}
+
+	try := append([]*byte{c.Kind()}, kinds...)
+	if c.Kind() != nil {
+		try = append(try, nil)
+	}
+	var firstErr error
+	for _, k := range try {
+		info.TriedKinds = append(info.TriedKinds, kindName(k))
+		u, err := c.DecodeToUint64WithKind(in, k)
+		if err == nil {
+			name := kindName(k)
+			info.ID, info.MatchedKind = &u, &name
+			r.Output = strconv.FormatUint(u, 10)
+			return r
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if !errors.Is(err, idcodec.ErrMACVerification) {
+			break // not kind-dependent, other kinds fail the same way
+		}
+	}
+	r.Error = firstErr.Error()
+	return r
+}
+
+func printInspection(w io.Writer, r result) {
+	info := r.Details.(*inspection)
+	fmt.Fprintf(w, "token:    %s\n", r.Input)
+	fmt.Fprintf(w, "length:   %d\n", info.Length)
+	if info.VersionChar != "" {
+		fmt.Fprintf(w, "version:  %q (match=%t)\n", info.VersionChar, info.VersionOK)
+	}
+	if info.Body != nil {
+		fmt.Fprintf(w, "body:     %d\n", *info.Body)
+	} else if info.BodyError != "" {
+		fmt.Fprintf(w, "body:     error: %s\n", info.BodyError)
+	}
+	fmt.Fprintf(w, "kinds:    %s\n", strings.Join(info.TriedKinds, ","))
+	if info.ID != nil {
+		fmt.Fprintf(w, "id:       %d (kind %s)\n", *info.ID, *info.MatchedKind)
+	} else {
+		fmt.Fprintf(w, "error:    %s\n", r.Error)
+	}
+	fmt.Fprintln(w)
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bronystylecrazy/gx/idcodec"
+)
+
+const testSecret = "0123456789abcdefghijklmnop"
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRunCommands(t *testing.T) {
+	c := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte(testSecret), MacLen: 8})
+	crock := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte(testSecret), Version: 10, MacLen: 8, Encoding: idcodec.EncodingCrockford32})
+	tok := c.EncodeUint64(42)
+	old := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("old-secret-0123456789"), Version: 1, MacLen: 8})
+
+	cases := []struct {
+		name  string
+		stdin string
+		args  []string
+		code  int
+		out   []string
+	}{
+		{"encode", "", []string{"encode", "-secret", testSecret, "42"}, 0, []string{tok + "\n"}},
+		{"encode bad id", "", []string{"encode", "-secret", testSecret, "x"}, 1, []string{"x\terror:"}},
+		{"decode", "", []string{"decode", "-secret", testSecret, tok}, 0, []string{"42\n"}},
+		{"decode stdin json", tok + "\n\nbogus\n", []string{"decode", "-secret", testSecret, "-json"}, 1,
+			[]string{`{"input":"` + tok + `","output":"42"}`, `{"input":"bogus","error":`}},
+		{"validate", "", []string{"validate", "-secret", testSecret, tok}, 0, []string{"ok\n"}},
+		{"inspect", "", []string{"inspect", "-secret", testSecret, tok}, 0,
+			[]string{"version:  \"0\" (match=true)", "id:       42 (kind none)"}},
+		{"inspect lowercase crockford", "", []string{"inspect", "-secret", testSecret, "-version", "10", "-encoding", "crockford32",
+			strings.ToLower(crock.EncodeUint64(7))}, 0, []string{"version:  \"a\" (match=true)", "id:       7"}},
+		{"inspect wrong version", "", []string{"inspect", "-secret", testSecret, "-version", "3", tok}, 1, []string{"(match=false)"}},
+		{"rotate", "", []string{"rotate", "-secret", testSecret, "-from-secret", "old-secret-0123456789", "-from-version", "1",
+			old.EncodeUint64(9)}, 0, []string{c.EncodeUint64(9) + "\n"}},
+	}
+	for _, tc := range cases {
+		code, out, stderr := runCLI(t, tc.stdin, tc.args...)
+		if code != tc.code {
+			t.Fatalf("%s: exit %d, want %d; stdout=%q stderr=%q", tc.name, code, tc.code, out, stderr)
+		}
+		for _, want := range tc.out {
+			if !strings.Contains(out, want) {
+				t.Fatalf("%s: missing %q in %q", tc.name, want, out)
+			}
+		}
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "idcodec.json")
+	if err := os.WriteFile(cfgPath, []byte(`{"secret":"`+testSecret+`","version":2,"mac_len":6}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fromFile := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte(testSecret), Version: 2, MacLen: 6})
+	if code, out, stderr := runCLI(t, "", "encode", "-config", cfgPath, "5"); code != 0 || out != fromFile.EncodeUint64(5)+"\n" {
+		t.Fatalf("config file: %d %q %q", code, out, stderr)
+	}
+
+	// Env beats the file, flags beat env.
+	t.Setenv("IDCODEC_VERSION", "3")
+	fromEnv := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte(testSecret), Version: 3, MacLen: 6})
+	if code, out, _ := runCLI(t, "", "encode", "-config", cfgPath, "5"); code != 0 || out != fromEnv.EncodeUint64(5)+"\n" {
+		t.Fatalf("env precedence: %d %q", code, out)
+	}
+	fromFlag := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte(testSecret), Version: 4, MacLen: 6})
+	if code, out, _ := runCLI(t, "", "encode", "-config", cfgPath, "-version", "4", "5"); code != 0 || out != fromFlag.EncodeUint64(5)+"\n" {
+		t.Fatalf("flag precedence: %d %q", code, out)
+	}
+	t.Setenv("IDCODEC_VERSION", "")
+
+	cases := []struct {
+		args   []string
+		stderr string
+	}{
+		{[]string{}, "usage:"},
+		{[]string{"frobnicate"}, "unknown command"},
+		{[]string{"encode", "1"}, "missing secret"},
+		{[]string{"encode", "-secret", "short", "1"}, "weak secret"},
+		{[]string{"encode", "-secret", testSecret, "-version", "300", "1"}, "version out of range"},
+		{[]string{"encode", "-secret", testSecret, "-mac-len", "x", "1"}, "mac-len"},
+		{[]string{"encode", "-secret", testSecret, "-encoding", "base64", "1"}, "unknown encoding"},
+		{[]string{"encode", "-secret", testSecret, "-kind", "UU", "1"}, "single byte"},
+		{[]string{"encode", "-secret", testSecret, "-kdf", "scrypt", "1"}, "unknown kdf"},
+		{[]string{"encode", "-config", filepath.Join(dir, "missing.json"), "1"}, "missing.json"},
+		{[]string{"inspect", "-secret", testSecret, "-kinds", "UO", "x"}, "single byte"},
+		{[]string{"rotate", "-secret", testSecret, "x"}, "from: missing secret"},
+		{[]string{"rotate", "-secret", testSecret, "-version", "1", "-from-secret", testSecret, "-from-version", "0", "-from-encoding", "base58", "x"},
+			"duplicate version char"},
+	}
+	for _, tc := range cases {
+		code, _, stderr := runCLI(t, "", tc.args...)
+		if code != 2 || !strings.Contains(stderr, tc.stderr) {
+			t.Fatalf("%v: exit %d, stderr %q; want 2 and %q", tc.args, code, stderr, tc.stderr)
+		}
+	}
+
+	if code, out, _ := runCLI(t, "", "encode", "-secret", "short", "-allow-weak-secret", "true", "1"); code != 0 || out == "" {
+		t.Fatalf("allow-weak-secret: %d %q", code, out)
+	}
+}
+
+func TestInspectJSON(t *testing.T) {
+	c := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte(testSecret), MacLen: 8})
+	code, out, _ := runCLI(t, "", "inspect", "-secret", testSecret, "-json", c.EncodeUint64(11))
+	var r struct {
+		Output  string
+		Details inspection
+	}
+	if err := json.Unmarshal([]byte(out), &r); err != nil || code != 0 {
+		t.Fatalf("json: %d %q %v", code, out, err)
+	}
+	if !r.Details.VersionOK || r.Details.ID == nil || *r.Details.ID != 11 || r.Output != "11" {
+		t.Fatalf("details: %+v", r)
+	}
+}
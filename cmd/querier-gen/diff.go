@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeDiff prints a unified diff of a and b with 3 lines of context. It is
+// an O(n*m) LCS diff, fine for generated files of a few hundred lines.
+func writeDiff(w io.Writer, name string, a, b []byte) {
+	x := strings.SplitAfter(string(a), "\n")
+	y := strings.SplitAfter(string(b), "\n")
+	if len(x) > 0 && x[len(x)-1] == "" {
+		x = x[:len(x)-1]
+	}
+	if len(y) > 0 && y[len(y)-1] == "" {
+		y = y[:len(y)-1]
+	}
+
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+		ai   int // line index in a before this line
+		bi   int
+	}
+	var ops []line
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, line{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, line{'-', x[i], i, j})
+			i++
+		default:
+			ops = append(ops, line{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	const ctx = 3
+	fmt.Fprintf(w, "--- %s\n+++ %s (generated)\n", name, name)
+	for k := 0; k < len(ops); {
+		if ops[k].op == ' ' {
+			k++
+			continue
+		}
+		start := max(k-ctx, 0)
+		end := k
+		for end < len(ops) {
+			if ops[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].op == ' ' {
+				run++
+			}
+			if run-end > 2*ctx || run == len(ops) {
+				end = min(end+ctx, len(ops))
+				break
+			}
+			end = run
+		}
+		var na, nb int
+		for _, l := range ops[start:end] {
+			if l.op != '+' {
+				na++
+			}
+			if l.op != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", ops[start].ai+1, na, ops[start].bi+1, nb)
+		for _, l := range ops[start:end] {
+			fmt.Fprintf(w, "%c%s", l.op, l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				fmt.Fprintln(w)
+			}
+		}
+		k = end
+	}
+}
@@ -0,0 +1,158 @@
+// Command querier-gen regenerates the Querier interface from the
+// `(q *querier)` methods in querier_*.go files; methods marked
+// //querier:ignore are left out, and with -retry only methods marked
+// //querier:retry are retried. Typical use:
+//
+//	//go:generate go run github.com/bronystylecrazy/gx/cmd/querier-gen
+//
+// -dry-run prints the output, -diff prints a diff against the file on disk
+// and -check exits 1 when the file is stale, for CI; -dry-run cannot be
+// combined with the other two. -watch keeps the files up to date as
+// querier_*.go files are saved, until interrupted; it cannot be combined
+// with any of the three.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bronystylecrazy/gx/generator"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("querier-gen", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	var opts generator.Options
+	fset.StringVar(&opts.Package, "package", "", "package name (default $GOPACKAGE, else the directory name)")
+	fset.StringVar(&opts.Dir, "dir", ".", "directory holding the querier_*.go files")
+	fset.StringVar(&opts.Output, "output", "querier.go", "output file name inside -dir")
+	fset.StringVar(&opts.Interface, "interface", "Querier", "generated interface name")
+	fset.StringVar(&opts.Backend, "backend", "gorm", "backend template: "+strings.Join(generator.Backends, ", "))
+	fset.StringVar(&opts.Template, "template", "", "text/template file overriding the built-in blocks")
+	receivers := fset.String("receivers", "querier", "comma-separated receiver types whose methods form the interface; receivers besides querier need a -template redefining \"backend\"")
+	fset.BoolVar(&opts.Split, "split", false, "emit one <File><interface> per querier_<file>.go and embed them")
+	fset.BoolVar(&opts.Mock, "mock", false, "also generate mock_<output> with a recording mock")
+	fset.BoolVar(&opts.Logging, "logging", false, "also generate logging_<output> with a zap logging decorator")
+	fset.BoolVar(&opts.Retry, "retry", false, "also generate retry_<output> with a decorator retrying //querier:retry methods")
+	dryRun := fset.Bool("dry-run", false, "print the generated file instead of writing it")
+	diff := fset.Bool("diff", false, "print a diff against the existing file instead of writing it")
+	check := fset.Bool("check", false, "exit 1 if the existing file is out of date; write nothing")
+	watch := fset.Bool("watch", false, "keep the generated files up to date as sources change, until interrupted")
+	debounce := fset.Duration("debounce", 200*time.Millisecond, "with -watch, wait this long after the last save")
+	poll := fset.Bool("poll", false, "with -watch, poll instead of using file notifications")
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *dryRun && (*diff || *check) {
+		fmt.Fprintln(stderr, "querier-gen: -dry-run cannot be combined with -diff or -check")
+		return 2
+	}
+	if *watch && (*dryRun || *diff || *check) {
+		fmt.Fprintln(stderr, "querier-gen: -watch cannot be combined with -dry-run, -diff or -check")
+		return 2
+	}
+	for _, r := range strings.Split(*receivers, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			opts.Receivers = append(opts.Receivers, r)
+		}
+	}
+	if opts.Package == "" && os.Getenv("GOPACKAGE") == "" {
+		abs, err := filepath.Abs(opts.Dir)
+		if err != nil {
+			fmt.Fprintf(stderr, "querier-gen: %v\n", err)
+			return 2
+		}
+		opts.Package = filepath.Base(abs)
+	}
+	// Generate from inside -dir, like go generate, so goimports resolves
+	// package names from that module.
+	dir := opts.Dir
+	if dir != "." {
+		if opts.Template != "" {
+			abs, err := filepath.Abs(opts.Template)
+			if err != nil {
+				fmt.Fprintf(stderr, "querier-gen: %v\n", err)
+				return 2
+			}
+			opts.Template = abs
+		}
+		wd, err := os.Getwd()
+		if err == nil {
+			err = os.Chdir(dir)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "querier-gen: %v\n", err)
+			return 2
+		}
+		defer os.Chdir(wd)
+		opts.Dir = "."
+	}
+
+	if *watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err := generator.Watch(ctx, generator.WatchOptions{Options: opts, Debounce: *debounce, ForcePoll: *poll, Out: stdout})
+		if err != nil {
+			fmt.Fprintf(stderr, "querier-gen: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	files, err := generator.GenerateFiles(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "querier-gen: %v\n", err)
+		return 1
+	}
+	code := 0
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name)
+		cur, err := os.ReadFile(f.Name)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(stderr, "querier-gen: %v\n", err)
+			return 1
+		}
+		stale := !bytes.Equal(cur, f.Content)
+
+		switch {
+		case *dryRun:
+			if len(files) > 1 {
+				fmt.Fprintf(stdout, "// ---- %s ----\n", path)
+			}
+			stdout.Write(f.Content)
+		case *diff || *check:
+			if stale && *diff {
+				writeDiff(stdout, path, cur, f.Content)
+			}
+			if stale && *check {
+				fmt.Fprintf(stderr, "querier-gen: %s is out of date; run go generate\n", path)
+				code = 1
+			}
+		case stale:
+			if err := os.WriteFile(f.Name, f.Content, 0o644); err != nil {
+				fmt.Fprintf(stderr, "querier-gen: %v\n", err)
+				return 1
+			}
+			fmt.Fprintf(stdout, "querier-gen: wrote %s\n", path)
+		}
+	}
+	return code
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCLI(args ...string) (int, string, string) {
+	var out, errOut bytes.Buffer
+	code := run(args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// writeFiles creates a module in a temp dir whose core/v1 package is named
+// v1, not core, plus the given querier files.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.25\n"
+	files["core/v1/pod.go"] = "package v1\n\ntype Pod struct{}\n"
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const podQuerier = `package store
+
+import (
+	"context"
+
+	"example.com/m/core/v1"
+)
+
+func (q *querier) GetPod(ctx context.Context, name string) (*v1.Pod, error) {
+	return nil, nil
+}
+`
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"querier_pod.go": podQuerier})
+	target := filepath.Join(dir, "querier.go")
+
+	code, out, stderr := runCLI("-dir", dir, "-package", "store", "-dry-run")
+	if code != 0 || !strings.Contains(out, "GetPod(ctx context.Context, name string) (*v1.Pod, error)") ||
+		!strings.Contains(out, `v1 "example.com/m/core/v1"`) {
+		t.Fatalf("dry-run: exit %d, stderr %q, out:\n%s", code, stderr, out)
+	}
+	if _, err := os.Stat(target); err == nil {
+		t.Fatalf("dry-run wrote %s", target)
+	}
+
+	if code, _, stderr := runCLI("-dir", dir, "-package", "store", "-check"); code != 1 || !strings.Contains(stderr, "out of date") {
+		t.Fatalf("check missing file: exit %d, stderr %q", code, stderr)
+	}
+	if code, out, _ := runCLI("-dir", dir, "-package", "store", "-diff"); code != 0 || !strings.Contains(out, "+type Querier interface {") {
+		t.Fatalf("diff: exit %d, out:\n%s", code, out)
+	}
+
+	if code, out, stderr := runCLI("-dir", dir, "-package", "store", "-mock"); code != 0 ||
+		!strings.Contains(out, "wrote "+target) || !strings.Contains(out, "wrote "+filepath.Join(dir, "mock_querier.go")) {
+		t.Fatalf("write: exit %d, stderr %q, out %q", code, stderr, out)
+	}
+	if code, out, stderr := runCLI("-dir", dir, "-package", "store", "-mock", "-check"); code != 0 || out != "" || stderr != "" {
+		t.Fatalf("check fresh file: exit %d, out %q, stderr %q", code, out, stderr)
+	}
+	if code, out, _ := runCLI("-dir", dir, "-package", "store", "-mock"); code != 0 || out != "" {
+		t.Fatalf("rewrite of fresh files: exit %d, out %q", code, out)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"querier_pod.go": podQuerier})
+	cases := []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{[]string{"-dry-run", "-check"}, 2, "cannot be combined"},
+		{[]string{"-dry-run", "-diff"}, 2, "cannot be combined"},
+		{[]string{"-watch", "-check"}, 2, "cannot be combined"},
+		{[]string{"-watch", "-diff"}, 2, "cannot be combined"},
+		{[]string{"-watch", "-dry-run"}, 2, "cannot be combined"},
+		{[]string{"-no-such-flag"}, 2, "flag provided but not defined"},
+		{[]string{"-dir", filepath.Join(dir, "missing")}, 2, "missing"},
+		{[]string{"-dir", dir, "-package", "store", "-backend", "mongo"}, 1, `unknown backend "mongo"`},
+		{[]string{"-dir", dir, "-package", "store", "-template", "nope.tmpl"}, 1, "nope.tmpl"},
+	}
+	for _, tc := range cases {
+		code, _, stderr := runCLI(tc.args...)
+		if code != tc.code || !strings.Contains(stderr, tc.stderr) {
+			t.Fatalf("%v: exit %d, stderr %q; want %d and %q", tc.args, code, stderr, tc.code, tc.stderr)
+		}
+	}
+}
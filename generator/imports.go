@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// builtinImports lists what each built-in template uses beyond the querier
+// files' own imports, keyed by backend or wrapper template name. It feeds the
+// import-name conflict check, so every entry must be one the template really
+// references; TestBuiltinImportsUsed keeps it honest.
+var builtinImports = map[string][]string{
+	"gorm":    {"context", "go.uber.org/zap", "gorm.io/gorm"},
+	"sql":     {"context", "database/sql", "errors", "fmt", "go.uber.org/zap"},
+	"sqlx":    {"context", "errors", "fmt", "github.com/jmoiron/sqlx", "go.uber.org/zap"},
+	"pgx":     {"context", "errors", "github.com/jackc/pgx/v5", "github.com/jackc/pgx/v5/pgconn", "go.uber.org/zap"},
+	"mock":    {"context", "sync"},
+	"logging": {"context", "time", "go.uber.org/zap"},
+	"retry":   {"context", "database/sql/driver", "errors", "net", "time"},
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// assumedName returns the package name path binds when imported without an
+// alias, and false when that cannot be known without loading the package:
+// a /vN or .vN suffix, go- prefixes and dashes all hide the real name.
+func assumedName(p string) (string, bool) {
+	name := path.Base(p)
+	if majorVersion.MatchString(name) || !token.IsIdentifier(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// templateImport is an import of a built-in template.
+type templateImport struct {
+	Path     string
+	Template string // backend or wrapper template name
+}
+
+// templateNames maps the names bound by the built-in imports of the backend
+// and the wrappers opts renders to those imports. Every builtinImports path
+// binds its last element, or the one before a /vN suffix.
+func templateNames(opts Options) map[string]templateImport {
+	names := map[string]templateImport{}
+	tmpls := []string{opts.Backend}
+	for _, f := range extraFiles(opts) {
+		tmpls = append(tmpls, f.tmpl)
+	}
+	for _, tmpl := range tmpls {
+		for _, p := range builtinImports[tmpl] {
+			name := path.Base(p)
+			if majorVersion.MatchString(name) {
+				name = path.Base(path.Dir(p))
+			}
+			if _, ok := names[name]; !ok {
+				names[name] = templateImport{Path: p, Template: tmpl}
+			}
+		}
+	}
+	return names
+}
+
+// importLines merges the import lines, standard library first, with a blank
+// entry between the two groups.
+func importLines(lines ...[]string) []string {
+	seen := map[string]bool{}
+	var std, other []string
+	for _, ls := range lines {
+		for _, l := range ls {
+			if seen[l] {
+				continue
+			}
+			seen[l] = true
+			p := l[strings.Index(l, `"`)+1:]
+			if strings.Contains(strings.SplitN(p, "/", 2)[0], ".") {
+				other = append(other, l)
+			} else {
+				std = append(std, l)
+			}
+		}
+	}
+	byPath := func(s []string) {
+		sort.Slice(s, func(i, j int) bool {
+			return s[i][strings.Index(s[i], `"`):] < s[j][strings.Index(s[j], `"`):]
+		})
+	}
+	byPath(std)
+	byPath(other)
+	if len(std) > 0 && len(other) > 0 {
+		std = append(std, "")
+	}
+	return append(std, other...)
+}
+
+// quoted turns import paths into import lines.
+func quoted(paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = strconv.Quote(p)
+	}
+	return out
+}
+
+// fixImports runs goimports on generated code as if it were the file
+// filename: unused imports are dropped and the result is gofmt'ed. Package
+// names are resolved from the module of the working directory, as under go
+// generate, so imports of packages outside it are kept only if their name is
+// the one goimports assumes.
+func fixImports(filename string, src []byte) ([]byte, error) {
+	return imports.Process(filename, src, &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+}
@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAssumedName(t *testing.T) {
+	cases := map[string]string{
+		"context":                                     "context",
+		"database/sql/driver":                         "driver",
+		"gorm.io/gorm":                                "gorm",
+		"github.com/bronystylecrazy/gx":               "gx",
+		"github.com/jackc/pgx/v5":                     "",
+		"k8s.io/api/core/v1":                          "",
+		"gopkg.in/yaml.v3":                            "",
+		"github.com/mattn/go-isatty":                  "",
+		"github.com/influxdata/influxdb-client-go/v2": "",
+	}
+	for path, want := range cases {
+		got, ok := assumedName(path)
+		if got != want || ok != (want != "") {
+			t.Fatalf("%s: got %q %v, want %q", path, got, ok, want)
+		}
+	}
+}
+
+func TestBuiltinImportsUsed(t *testing.T) {
+	dir := typesDir(t)
+	for _, b := range Backends {
+		files, err := GenerateFiles(Options{Package: "store", Dir: dir, Backend: b, Mock: true, Logging: true, Retry: true})
+		if err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+		for _, f := range files {
+			tmpl := b
+			if f.Name != "querier.go" {
+				tmpl = strings.TrimSuffix(f.Name, "_querier.go")
+			}
+			// goimports drops what the output does not use.
+			node, err := parser.ParseFile(token.NewFileSet(), f.Name, f.Content, parser.ImportsOnly)
+			if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+			got := map[string]bool{}
+			for _, imp := range node.Imports {
+				got[strings.Trim(imp.Path.Value, `"`)] = true
+			}
+			for _, p := range builtinImports[tmpl] {
+				if !got[p] {
+					t.Fatalf("%s: builtinImports[%q] lists %q, but %s does not import it", b, tmpl, p, f.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestImportLines(t *testing.T) {
+	got := importLines([]string{`"github.com/google/uuid"`, `"time"`, `u2 "github.com/google/uuid"`}, quoted([]string{"context", "time"}))
+	want := []string{`"context"`, `"time"`, "", `"github.com/google/uuid"`, `u2 "github.com/google/uuid"`}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// writeModule lays out a throwaway module whose packages are named
+// differently from their import paths.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.25\n"
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFixImports(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"core/v1/pod.go":          "package v1\n\ntype Pod struct{}\n",
+		"influx-client/client.go": "package influxdb2\n\ntype Client struct{}\n",
+	})
+	src := `package p
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"example.com/m/core/v1"
+	"example.com/m/influx-client"
+	u2 "github.com/google/uuid"
+)
+
+type T struct {
+	Ctx context.Context
+	Pod v1.Pod
+	C   influxdb2.Client
+	X   u2.UUID
+}
+`
+	t.Chdir(dir)
+	out, err := fixImports(filepath.Join(dir, "querier.go"), []byte(src))
+	if err != nil {
+		t.Fatalf("fixImports: %v", err)
+	}
+	want := `import (
+	"context"
+
+	v1 "example.com/m/core/v1"
+	influxdb2 "example.com/m/influx-client"
+	u2 "github.com/google/uuid"
+)`
+	if !strings.Contains(string(out), want) {
+		t.Fatalf("got:\n%s", out)
+	}
+}
@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Field is one parameter or result of a querier method.
+type Field struct {
+	Name     string // as written; empty for unnamed
+	Type     string // without the ... of a variadic param
+	Variadic bool
+}
+
+func fieldsOf(fset *token.FileSet, fields *ast.FieldList) []Field {
+	if fields == nil {
+		return nil
+	}
+	var out []Field
+	for _, f := range fields.List {
+		typ, variadic := f.Type, false
+		if e, ok := typ.(*ast.Ellipsis); ok {
+			typ, variadic = e.Elt, true
+		}
+		fd := Field{Type: exprToString(fset, typ), Variadic: variadic}
+		if len(f.Names) == 0 {
+			out = append(out, fd)
+			continue
+		}
+		for _, name := range f.Names {
+			fd.Name = name.Name
+			out = append(out, fd)
+		}
+	}
+	return out
+}
+
+// The helpers below name params p0..pN and results r0..rN so wrappers can
+// forward them whatever the original names were.
+
+func (f Field) typ() string {
+	if f.Variadic {
+		return "..." + f.Type
+	}
+	return f.Type
+}
+
+// Args renders the params as a declaration: "p0 context.Context, p1 ...string".
+func (m InterfaceMethod) Args() string {
+	parts := make([]string, len(m.ParamFields))
+	for i, f := range m.ParamFields {
+		parts[i] = fmt.Sprintf("p%d %s", i, f.typ())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CallArgs renders the params as call arguments: "p0, p1...".
+func (m InterfaceMethod) CallArgs() string {
+	parts := make([]string, len(m.ParamFields))
+	for i, f := range m.ParamFields {
+		parts[i] = fmt.Sprintf("p%d", i)
+		if f.Variadic {
+			parts[i] += "..."
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ArgNames renders the params as plain values: "p0, p1".
+func (m InterfaceMethod) ArgNames() string {
+	parts := make([]string, len(m.ParamFields))
+	for i := range m.ParamFields {
+		parts[i] = fmt.Sprintf("p%d", i)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NamedResults renders the results as "(r0 T0, r1 error)", or "" for none.
+func (m InterfaceMethod) NamedResults() string {
+	if len(m.ResultFields) == 0 {
+		return ""
+	}
+	parts := make([]string, len(m.ResultFields))
+	for i, f := range m.ResultFields {
+		parts[i] = fmt.Sprintf("r%d %s", i, f.Type)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// ResultNames renders "r0, r1".
+func (m InterfaceMethod) ResultNames() string {
+	parts := make([]string, len(m.ResultFields))
+	for i := range m.ResultFields {
+		parts[i] = fmt.Sprintf("r%d", i)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FuncType renders the method as a func type: "func(context.Context, ...string) (T, error)".
+func (m InterfaceMethod) FuncType() string {
+	params := make([]string, len(m.ParamFields))
+	for i, f := range m.ParamFields {
+		params[i] = f.typ()
+	}
+	sig := "func(" + strings.Join(params, ", ") + ")"
+	switch len(m.ResultFields) {
+	case 0:
+	case 1:
+		sig += " " + m.ResultFields[0].Type
+	default:
+		results := make([]string, len(m.ResultFields))
+		for i, f := range m.ResultFields {
+			results[i] = f.Type
+		}
+		sig += " (" + strings.Join(results, ", ") + ")"
+	}
+	return sig
+}
+
+// ErrResult returns the name of the trailing error result, or "".
+func (m InterfaceMethod) ErrResult() string {
+	if n := len(m.ResultFields); n > 0 && m.ResultFields[n-1].Type == "error" {
+		return fmt.Sprintf("r%d", n-1)
+	}
+	return ""
+}
+
+// CtxArg returns the name of a leading context.Context param, or "".
+func (m InterfaceMethod) CtxArg() string {
+	if len(m.ParamFields) > 0 && m.ParamFields[0].Type == "context.Context" && !m.ParamFields[0].Variadic {
+		return "p0"
+	}
+	return ""
+}
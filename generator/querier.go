@@ -1,228 +1,372 @@
 package generator
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/printer"
 	"go/token"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"sort"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 // InterfaceMethod represents a method in an interface
 type InterfaceMethod struct {
 	Name    string
-	Params  []string
-	Results []string
+	Doc     string   // doc comment text, without comment markers
+	Params  []string // "name T", or just "T" for unnamed params
+	Results []string // "name T", or just "T" for unnamed results
+
+	ParamFields  []Field // Params split up, for wrapper templates
+	ResultFields []Field
+	Retry        bool // marked //querier:retry, so safe to call again
+
+	pos  token.Position
+	recv string // receiver type name
+}
+
+// Signature renders the method as it appears in the interface.
+func (m InterfaceMethod) Signature() string {
+	sig := m.Name + "(" + strings.Join(m.Params, ", ") + ")"
+	switch {
+	case len(m.Results) == 0:
+	case len(m.Results) == 1 && !strings.Contains(m.Results[0], " "):
+		sig += " " + m.Results[0]
+	default:
+		sig += " (" + strings.Join(m.Results, ", ") + ")"
+	}
+	return sig
+}
+
+// Options configures a generator run.
+type Options struct {
+	Package   string // package clause of the output
+	Dir       string // directory holding the querier_*.go files, default "."
+	Output    string // output file name inside Dir, default "querier.go"
+	Interface string // generated interface name, default "Querier"
+	Backend   string // one of Backends, default "gorm"
+	Template  string // optional template file overriding the built-in blocks
+
+	// Receivers names the receiver types, pointer or value, whose methods
+	// make up the interface; default "querier". Receivers besides querier
+	// need a template redefining "backend" that builds them, e.g. by
+	// embedding them with the "embeds" template and binding them in WithTx.
+	Receivers []string
+	// Split groups the methods of each querier_<name>.go file into a
+	// <Name><Interface> interface, which the aggregate interface embeds.
+	Split bool
+
+	Mock    bool // also write mock_<Output> with Mock<Interface>
+	Logging bool // also write logging_<Output> with NewLogging<Interface>
+	Retry   bool // also write retry_<Output> with NewRetry<Interface>, for //querier:retry methods
+}
+
+func (o Options) withDefaults() Options {
+	if o.Dir == "" {
+		o.Dir = "."
+	}
+	if o.Output == "" {
+		o.Output = "querier.go"
+	}
+	if o.Interface == "" {
+		o.Interface = "Querier"
+	}
+	if o.Backend == "" {
+		o.Backend = "gorm"
+	}
+	if len(o.Receivers) == 0 {
+		o.Receivers = []string{"querier"}
+	}
+	if o.Package == "" {
+		o.Package = os.Getenv("GOPACKAGE") // set by go generate
+	}
+	return o
 }
 
-// findQuerierFiles locates all `_querier.go` files except `querier.go`
-func findQuerierFiles(root string) ([]string, error) {
+// Path returns the output file path.
+func (o Options) Path() string {
+	o = o.withDefaults()
+	return filepath.Join(o.Dir, o.Output)
+}
+
+// findQuerierFiles locates the `querier_*.go` files in dir, except the output
+// file and tests, in lexical order. Subdirectories are other packages and
+// are not searched.
+func findQuerierFiles(dir, output string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
 	var files []string
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, "querier_") || !strings.HasSuffix(name, ".go") ||
+			strings.HasSuffix(name, "_test.go") || name == output {
+			continue
 		}
-		if !info.IsDir() && strings.HasPrefix(info.Name(), "querier_") && info.Name() != "querier.go" {
-			files = append(files, path)
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files, nil
+}
+
+// importSpec is an import of a querier file, keyed by the name it binds.
+type importSpec struct {
+	Name string // local name: the alias or assumedName, "" when unknown
+	Line string // as written to the output: `"path"` or `alias "path"`
+	Path string
+	Pos  token.Position
+}
+
+// Directives in a method's doc comment: ignoreDirective keeps the method out
+// of the interface, retryDirective lets NewRetry<Interface> retry it.
+const (
+	ignoreDirective = "//querier:ignore"
+	retryDirective  = "//querier:retry"
+)
+
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == directive {
+			return true
 		}
-		return nil
-	})
-	return files, err
+	}
+	return false
+}
+
+// receiverName returns the type name of a T or *T receiver, and whether T
+// takes type parameters, as in *T[K].
+func receiverName(recv *ast.FieldList) (name string, generic bool) {
+	if recv == nil || len(recv.List) != 1 {
+		return "", false
+	}
+	typ := recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	switch x := typ.(type) {
+	case *ast.IndexExpr:
+		typ, generic = x.X, true
+	case *ast.IndexListExpr:
+		typ, generic = x.X, true
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name, generic
+	}
+	return "", false
 }
 
-// parseQuerierMethods extracts all `(q *querier)` methods and import statements
-func parseQuerierMethods(filename string) ([]InterfaceMethod, []string, error) {
+// parseQuerierMethods extracts the methods on the given receivers, except
+// those marked //querier:ignore, and the imports their signatures use.
+// Imports are file-scoped, so an import no signature refers to is dropped
+// here rather than checked against other files; imports whose name is
+// unknown are kept for goimports.
+func parseQuerierMethods(filename string, receivers []string) ([]InterfaceMethod, []importSpec, error) {
 	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filename, nil, parser.AllErrors)
+	node, err := parser.ParseFile(fset, filename, nil, parser.AllErrors|parser.ParseComments)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var methods []InterfaceMethod
-	var imports []string
+	var imports []importSpec
+	var errs []error
+	used := map[string]bool{} // package names referenced by the signatures
 
 	// Extract imports
 	for _, imp := range node.Imports {
-		imports = append(imports, imp.Path.Value) // e.g., `"context"`
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name, _ := assumedName(path)
+		spec := importSpec{Name: name, Line: imp.Path.Value, Path: path, Pos: fset.Position(imp.Pos())}
+		if imp.Name != nil {
+			if imp.Name.Name == "_" {
+				continue
+			}
+			spec.Name = imp.Name.Name
+			spec.Line = imp.Name.Name + " " + imp.Path.Value
+		}
+		imports = append(imports, spec)
 	}
 
 	// Extract methods
-	ast.Inspect(node, func(n ast.Node) bool {
-		if fn, ok := n.(*ast.FuncDecl); ok && fn.Recv != nil {
-			if len(fn.Recv.List) == 1 {
-				recvType, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
-				if ok {
-					ident, ok := recvType.X.(*ast.Ident)
-					if ok && ident.Name == "querier" {
-						method := InterfaceMethod{Name: fn.Name.Name}
-
-						for _, param := range fn.Type.Params.List {
-							for _, name := range param.Names {
-								method.Params = append(method.Params, fmt.Sprintf("%s %s", name.Name, exprToString(param.Type)))
-							}
-						}
-
-						if fn.Type.Results != nil {
-							for _, result := range fn.Type.Results.List {
-								method.Results = append(method.Results, exprToString(result.Type))
-							}
-						}
-
-						methods = append(methods, method)
-					}
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		recv, generic := receiverName(fn.Recv)
+		if !slices.Contains(receivers, recv) || hasDirective(fn.Doc, ignoreDirective) {
+			continue
+		}
+		if generic {
+			// The interface would need the receiver's type parameters.
+			errs = append(errs, fmt.Errorf("%s: method %s has generic receiver %s, which the generator does not support; mark it %s to leave it out",
+				fset.Position(fn.Name.Pos()), fn.Name.Name, exprToString(fset, fn.Recv.List[0].Type), ignoreDirective))
+			continue
+		}
+		methods = append(methods, InterfaceMethod{
+			Name:    fn.Name.Name,
+			Doc:     fn.Doc.Text(),
+			Params:  fieldsToStrings(fset, fn.Type.Params),
+			Results: fieldsToStrings(fset, fn.Type.Results),
+
+			ParamFields:  fieldsOf(fset, fn.Type.Params),
+			ResultFields: fieldsOf(fset, fn.Type.Results),
+			Retry:        hasDirective(fn.Doc, retryDirective),
+
+			pos:  fset.Position(fn.Name.Pos()),
+			recv: recv,
+		})
+		ast.Inspect(fn.Type, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok {
+					used[x.Name] = true
 				}
 			}
-		}
-		return true
+			return true
+		})
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	imports = slices.DeleteFunc(imports, func(imp importSpec) bool {
+		return imp.Name != "" && imp.Name != "." && !used[imp.Name]
 	})
 
 	return methods, imports, nil
 }
 
-// exprToString converts an AST expression to its string representation
-func exprToString(expr ast.Expr) string {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		return t.Name
-	case *ast.StarExpr:
-		return "*" + exprToString(t.X)
-	case *ast.ArrayType:
-		return "[]" + exprToString(t.Elt)
-	case *ast.SelectorExpr:
-		return exprToString(t.X) + "." + t.Sel.Name
-	case *ast.FuncType:
-		return "func(...)"
-	default:
-		return fmt.Sprintf("%T", expr)
-	}
-}
-
-// generateQuerierFileContent creates the final `querier.go` file content
-func generateQuerierFileContent(packageName string, imports []string, methods []InterfaceMethod) string {
-	var sb strings.Builder
-
-	// Add auto-generated comment
-	sb.WriteString(fmt.Sprintf(`// Code generated. DO NOT EDIT.
-// Code generated. DO NOT EDIT.
-// Code generated. DO NOT EDIT.
-
-package %v
-
-`, packageName))
+// collectQuerier parses every file and merges their methods and the imports
+// those use. All parse errors, duplicate methods and import names used by
+// signatures but bound to different paths, by each other or by the templates
+// opts renders, are reported together, with file:line positions. Imports
+// whose name cannot be known without loading them are left for goimports to
+// sort out.
+func collectQuerier(files []string, opts Options) ([]InterfaceMethod, []string, error) {
+	var errs []error
+	var methods []InterfaceMethod
+	var lines []string
+	byMethod := map[string]InterfaceMethod{}
+	byName := map[string]importSpec{}
+	seenLine := map[string]bool{}
+	builtin := templateNames(opts)
 
-	// Add import block
-	if len(imports) > 0 {
-		sb.WriteString("import (\n")
-		importSet := make(map[string]struct{})
-		for _, imp := range imports {
-			importSet[imp] = struct{}{}
+	for _, file := range files {
+		ms, imports, err := parseQuerierMethods(file, opts.Receivers)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, m := range ms {
+			if prev, dup := byMethod[m.Name]; dup {
+				errs = append(errs, fmt.Errorf("%s: duplicate querier method %s, also declared at %s", m.pos, m.Name, prev.pos))
+				continue
+			}
+			byMethod[m.Name] = m
+			methods = append(methods, m)
 		}
-		for imp := range importSet {
-			sb.WriteString("\t" + imp + "\n")
+		for _, imp := range imports {
+			if imp.Name != "" && imp.Name != "." {
+				if b, ok := builtin[imp.Name]; ok && b.Path != imp.Path {
+					errs = append(errs, fmt.Errorf("%s: import name %s refers to %q, but the %s template imports %q", imp.Pos, imp.Name, imp.Path, b.Template, b.Path))
+				}
+				if prev, ok := byName[imp.Name]; ok && prev.Path != imp.Path {
+					errs = append(errs, fmt.Errorf("%s: import name %s refers to %q, but to %q at %s", imp.Pos, imp.Name, imp.Path, prev.Path, prev.Pos))
+					continue
+				}
+				byName[imp.Name] = imp
+			}
+			if !seenLine[imp.Line] {
+				seenLine[imp.Line] = true
+				lines = append(lines, imp.Line)
+			}
 		}
-		sb.WriteString(")\n\n")
 	}
-
-	// Sort methods alphabetically
-	sort.Slice(methods, func(i, j int) bool {
-		return methods[i].Name < methods[j].Name
-	})
-
-	// Start interface definition
-	sb.WriteString("type Querier interface {\n")
-	sb.WriteString("\tWithTx(TxFunc) error\n")
-	sb.WriteString("\n")
-	sb.WriteString("\t// Auto Generated\n")
-	for _, method := range methods {
-		sb.WriteString(fmt.Sprintf("\t%s(%s) (%s)\n",
-			method.Name,
-			strings.Join(method.Params, ", "),
-			strings.Join(method.Results, ", "),
-		))
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
 	}
-	sb.WriteString("}\n\n")
-
-	// Add struct and constructor
-	sb.WriteString(`
-func NewQuerier(db *gorm.DB, log *zap.Logger) Querier {
-	return &querier{db: db, log: log}
-}
-
-type querier struct {
-	db *gorm.DB
-    log *zap.Logger
+	return methods, lines, nil
 }
 
-type TxFunc func(Querier) error
-
-func (q *querier) WithTx(fn TxFunc) error {
-	return q.db.Transaction(func(tx *gorm.DB) error {
-		q := NewQuerier(tx, q.log)
-		return fn(q)
-	})
-}
-`)
-
-	return sb.String()
+// exprToString converts an AST expression to its source form
+func exprToString(fset *token.FileSet, expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, expr); err != nil {
+		return fmt.Sprintf("/* %v */", err)
+	}
+	return buf.String()
 }
 
-// writeQuerierFile replaces `querier.go` with new content
-func writeQuerierFile(filename string, content string) error {
-	return ioutil.WriteFile(filename, []byte(content), 0644)
+// fieldsToStrings renders a parameter or result list, one entry per name,
+// keeping unnamed entries unnamed.
+func fieldsToStrings(fset *token.FileSet, fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var out []string
+	for _, f := range fields.List {
+		typ := exprToString(fset, f.Type)
+		if len(f.Names) == 0 {
+			out = append(out, typ)
+			continue
+		}
+		for _, name := range f.Names {
+			out = append(out, name.Name+" "+typ)
+		}
+	}
+	return out
 }
 
-// postProcessQuerierFile runs `goimports` to fix missing or unused imports
-func postProcessQuerierFile(filename string) error {
-	cmd := exec.Command("goimports", "-w", filename)
-	err := cmd.Run()
+// Generate renders the querier file for opts: parsed, import-fixed and
+// gofmt'ed in-process, without writing it.
+func Generate(opts Options) ([]byte, error) {
+	opts.Mock, opts.Logging, opts.Retry = false, false, false
+	files, err := GenerateFiles(opts)
 	if err != nil {
-		return fmt.Errorf("failed to run goimports: %w", err)
+		return nil, err
 	}
-	return nil
+	return files[0].Content, nil
 }
 
-func GenerateQuerier(packageName, dir, querierFileName string) error {
-	querierFile := filepath.Join(dir, querierFileName)
-	// Step 1: Find all _querier.go files (excluding querier.go)
-	files, err := findQuerierFiles(dir)
+// GenerateFiles renders the querier file followed by the optional mock,
+// logging and retry files selected in opts.
+func GenerateFiles(opts Options) ([]File, error) {
+	opts = opts.withDefaults()
+	if opts.Package == "" {
+		return nil, fmt.Errorf("generator: package name is required")
+	}
+	// Step 1: Find all querier_*.go files (excluding the output)
+	files, err := findQuerierFiles(opts.Dir, opts.Output)
 	if err != nil {
-		fmt.Println("Error finding files:", err)
-		return err
+		return nil, err
 	}
 
-	var allMethods []InterfaceMethod
-	var allImports []string
-
-	for _, file := range files {
-		// Step 2: Extract methods and imports
-		methods, imports, err := parseQuerierMethods(file)
-		if err != nil {
-			fmt.Println("Error parsing file:", file, err)
-			continue
-		}
-		allMethods = append(allMethods, methods...)
-		allImports = append(allImports, imports...)
+	// Step 2: Extract methods and imports
+	methods, imports, err := collectQuerier(files, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Step 3: Generate the entire `querier.go` content
-	querierContent := generateQuerierFileContent(packageName, allImports, allMethods)
+	// Step 3: Render the files and fix their imports
+	return renderQuerierFiles(opts, imports, methods)
+}
 
-	// Step 4: Write new querier.go file
-	err = writeQuerierFile(querierFile, querierContent)
+// GenerateQuerier regenerates dir/querierFileName in place.
+func GenerateQuerier(packageName, dir, querierFileName string) error {
+	opts := Options{Package: packageName, Dir: dir, Output: querierFileName}
+	out, err := Generate(opts)
 	if err != nil {
-		fmt.Println("Error writing querier.go:", err)
 		return err
 	}
-
-	// Step 5: Run goimports to fix import issues
-	err = postProcessQuerierFile(querierFile)
-	if err != nil {
-		fmt.Println("Warning: goimports failed, you may have unused or missing imports.")
+	if err := os.WriteFile(opts.Path(), out, 0644); err != nil {
+		fmt.Println("Error writing querier.go:", err)
 		return err
 	}
 
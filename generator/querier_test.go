@@ -0,0 +1,311 @@
+package generator
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const typesQuerier = `package store
+
+import (
+	"context"
+	"io"
+)
+
+type Page[T any] struct{ Items []T }
+
+// Stream sends rows to ch.
+//
+// It closes ch when done.
+func (q *querier) Stream(ctx context.Context, ch chan<- map[string][]int, _ func(int) (bool, error)) error {
+	return nil
+}
+
+func (q *querier) Paged(ctx context.Context, filter struct{ Name string }, tags ...string) (page Page[int], total int64, err error) {
+	return
+}
+
+//querier:retry
+func (q *querier) Raw(context.Context, [16]byte, interface{ io.Reader }) (<-chan *[4]int, error) {
+	return nil, nil
+}
+
+func (q *querier) Count() int { return 0 }
+
+func (q *querier) Touch() {}
+`
+
+// typesDir returns a fresh directory holding typesQuerier.
+func typesDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "querier_types.go"), []byte(typesQuerier), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGenerateRendersAllTypes(t *testing.T) {
+	dir := typesDir(t)
+	out, err := Generate(Options{Package: "store", Dir: dir})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		"\t// Stream sends rows to ch.\n\t//\n\t// It closes ch when done.\n\tStream(ctx context.Context, ch chan<- map[string][]int, _ func(int) (bool, error)) error\n",
+		"Paged(ctx context.Context, filter struct{ Name string }, tags ...string) (page Page[int], total int64, err error)\n",
+		"Raw(context.Context, [16]byte, interface{ io.Reader }) (<-chan *[4]int, error)\n",
+		"Count() int\n",
+		"Touch()\n",
+		"\t\"io\"\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "*ast.") || strings.Contains(got, "func(...)") {
+		t.Fatalf("unrendered expression in:\n%s", got)
+	}
+}
+
+func TestGenerateBackends(t *testing.T) {
+	dir := typesDir(t)
+	wants := map[string][]string{
+		"gorm": {"func NewQuerier(db *gorm.DB, log *zap.Logger) Querier", "q.db.WithContext(ctx).Transaction("},
+		"sql":  {"func NewQuerier(db *sql.DB, log *zap.Logger) Querier", "q.db.BeginTx(ctx, nil)", `"SAVEPOINT "+sp`},
+		"sqlx": {"func NewQuerier(db *sqlx.DB, log *zap.Logger) Querier", "q.db.BeginTxx(ctx, nil)", `"RELEASE SAVEPOINT "+sp`},
+		"pgx":  {"func NewQuerier(db DBTX, log *zap.Logger) Querier", "tx.Commit(ctx)", `"github.com/jackc/pgx/v5/pgconn"`},
+	}
+	for _, b := range Backends {
+		out, err := Generate(Options{Package: "store", Dir: dir, Backend: b})
+		if err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+		for _, want := range append(wants[b], "WithTxContext(ctx context.Context, fn TxFunc) error\n") {
+			if !strings.Contains(string(out), want) {
+				t.Fatalf("%s: missing %q in:\n%s", b, want, out)
+			}
+		}
+	}
+	if _, err := Generate(Options{Package: "store", Dir: dir, Backend: "mongo"}); err == nil {
+		t.Fatalf("want error for unknown backend")
+	}
+}
+
+func TestGenerateUserTemplate(t *testing.T) {
+	dir := typesDir(t)
+	tmpl := filepath.Join(dir, "custom.tmpl")
+	custom := `{{define "backend"}}
+type querier struct{ db *Store }
+
+type TxFunc func({{.Interface}}) error
+{{end}}`
+	if err := os.WriteFile(tmpl, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Generate(Options{Package: "store", Dir: dir, Interface: "Repo", Template: tmpl})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, "type Repo interface {") || !strings.Contains(got, "type querier struct{ db *Store }") || strings.Contains(got, "gorm") {
+		t.Fatalf("override not applied:\n%s", got)
+	}
+}
+
+func TestGenerateFiles(t *testing.T) {
+	dir := typesDir(t)
+	files, err := GenerateFiles(Options{Package: "store", Dir: dir, Mock: true, Logging: true, Retry: true})
+	if err != nil {
+		t.Fatalf("GenerateFiles: %v", err)
+	}
+	wants := map[string][]string{
+		"querier.go":         {"type Querier interface {"},
+		"mock_querier.go":    {"type MockQuerier struct {", "PagedFunc         func(context.Context, struct{ Name string }, ...string)", "var _ Querier = (*MockQuerier)(nil)"},
+		"logging_querier.go": {"func NewLoggingQuerier(", "q.next.Paged(p0, p1, p2...)"},
+		"retry_querier.go":   {"func NewRetryQuerier(", "func IsTransient(err error) bool"},
+	}
+	if len(files) != len(wants) {
+		t.Fatalf("got %d files", len(files))
+	}
+	for _, f := range files {
+		for _, want := range wants[f.Name] {
+			if !strings.Contains(string(f.Content), want) {
+				t.Fatalf("%s: missing %q in:\n%s", f.Name, want, f.Content)
+			}
+		}
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGenerateGolden(t *testing.T) {
+	// basic/archive is another package: its querier_*.go must not be picked
+	// up, so ArchiveOrders is absent from the basic golden files.
+	cases := []Options{
+		{Package: "store", Dir: filepath.Join("testdata", "basic"), Mock: true},
+		{Package: "store", Dir: filepath.Join("testdata", "split"), Template: filepath.Join("testdata", "split", "backend.tmpl"),
+			Receivers: []string{"querier", "reports"}, Split: true},
+	}
+	for _, opts := range cases {
+		files, err := GenerateFiles(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", opts.Dir, err)
+		}
+		for _, f := range files {
+			golden := filepath.Join(opts.Dir, strings.TrimSuffix(f.Name, ".go")+".golden")
+			if *update {
+				if err := os.WriteFile(golden, f.Content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(f.Content, want) {
+				t.Fatalf("%s differs from %s:\n%s", f.Name, golden, f.Content)
+			}
+		}
+
+		// Output must not depend on map iteration or file order.
+		for i := 0; i < 10; i++ {
+			again, err := GenerateFiles(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j := range files {
+				if !bytes.Equal(again[j].Content, files[j].Content) {
+					t.Fatalf("run %d: %s is not deterministic", i, files[j].Name)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateConflicts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("querier_a.go", "package store\n\nimport log \"example.com/log\"\n\nfunc (q *querier) Get(l *log.Logger) error { return nil }\n")
+	write("querier_b.go", "package store\n\nimport \"log\"\n\nfunc (q *querier) Get(l *log.Logger) error { return nil }\n")
+	write("querier_c.go", "package store\n\nfunc (q *querier) Broken( {\n")
+	write("querier_d.go", "package store\n\nfunc (q *querier) Bad() int { return }}\n")
+
+	_, err := Generate(Options{Package: "store", Dir: dir})
+	if err == nil {
+		t.Fatalf("want error")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"querier_b.go:5:19: duplicate querier method Get, also declared at " + filepath.Join(dir, "querier_a.go") + ":5:19",
+		"querier_b.go:3:8: import name log refers to \"log\", but to \"example.com/log\" at " + filepath.Join(dir, "querier_a.go") + ":3:8",
+		"querier_c.go:3:",
+		"querier_d.go:3:",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("missing %q in:\n%s", want, msg)
+		}
+	}
+}
+
+func TestGenerateGenericReceiver(t *testing.T) {
+	dir := t.TempDir()
+	src := "package store\n\nimport \"context\"\n\ntype querier[T any] struct{}\n\nfunc (q *querier[T]) Get(ctx context.Context) (T, error) { var v T; return v, nil }\n\nfunc (q querier[K, V]) Put() {}\n"
+	if err := os.WriteFile(filepath.Join(dir, "querier_a.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Generate(Options{Package: "store", Dir: dir})
+	if err == nil {
+		t.Fatal("want error")
+	}
+	file := filepath.Join(dir, "querier_a.go")
+	for _, want := range []string{
+		file + ":7:22: method Get has generic receiver *querier[T]",
+		file + ":9:24: method Put has generic receiver querier[K, V]",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("missing %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestGenerateReceiversNeedBackend(t *testing.T) {
+	dir := filepath.Join("testdata", "split")
+	for _, b := range Backends {
+		_, err := GenerateFiles(Options{Package: "store", Dir: dir, Backend: b, Receivers: []string{"querier", "reports"}})
+		want := filepath.Join(dir, "querier_report_stats.go") + ":14:18: method DailyTotals has receiver reports"
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: got %v, want %q", b, err, want)
+		}
+	}
+}
+
+func TestGenerateTemplateImportConflicts(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "querier_a.go")
+	cases := []struct {
+		opts    Options
+		imports string
+		result  string // uses the import, so its name reaches the output
+		want    string
+	}{
+		{Options{Backend: "sql"}, `errors "github.com/pkg/errors"`, "errors.StackTrace", `import name errors refers to "github.com/pkg/errors", but the sql template imports "errors"`},
+		{Options{Backend: "pgx"}, `errors "github.com/pkg/errors"`, "errors.StackTrace", `import name errors refers to "github.com/pkg/errors", but the pgx template imports "errors"`},
+		{Options{Backend: "pgx"}, `"example.com/pgx"`, "*pgx.Conn", `import name pgx refers to "example.com/pgx", but the pgx template imports "github.com/jackc/pgx/v5"`},
+		{Options{Backend: "sqlx", Logging: true}, `time "example.com/clock"`, "time.Clock", `import name time refers to "example.com/clock", but the logging template imports "time"`},
+		{Options{Backend: "sqlx", Mock: true}, `sync "example.com/sync"`, "*sync.Map", `import name sync refers to "example.com/sync", but the mock template imports "sync"`},
+	}
+	for _, tc := range cases {
+		src := "package store\n\nimport " + tc.imports + "\n\nfunc (q *querier) Get() " + tc.result + " { return nil }\n"
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		opts := tc.opts
+		opts.Package, opts.Dir = "store", dir
+		_, err := GenerateFiles(opts)
+		if want := file + ":3:8: " + tc.want; err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: got %v, want %q", tc.imports, err, want)
+		}
+	}
+
+	// The same names are fine when the templates that bind them are off.
+	src := "package store\n\nimport (\n\ttime \"example.com/clock\"\n\tsync \"example.com/sync\"\n)\n\nfunc (q *querier) Get(time.Time, sync.Map) {}\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := collectQuerier([]string{file}, Options{Backend: "sqlx", Receivers: []string{"querier"}}); err != nil {
+		t.Fatalf("without wrappers: %v", err)
+	}
+}
+
+func TestGenerateUnusedImportNames(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// errors is bound differently in each file and by the sql template, but
+	// no signature refers to it, so nothing clashes in the output.
+	write("querier_a.go", "package store\n\nimport (\n\t\"context\"\n\n\terrors \"github.com/pkg/errors\"\n)\n\nfunc (q *querier) Get(ctx context.Context) error { return errors.New(\"\") }\n")
+	write("querier_b.go", "package store\n\nimport \"errors\"\n\nfunc (q *querier) Put() error { return errors.New(\"\") }\n")
+	for _, b := range Backends {
+		out, err := Generate(Options{Package: "store", Dir: dir, Backend: b})
+		if err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+		if strings.Contains(string(out), "github.com/pkg/errors") {
+			t.Fatalf("%s: unused import kept:\n%s", b, out)
+		}
+	}
+}
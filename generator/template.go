@@ -0,0 +1,192 @@
+package generator
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// Backends lists the built-in backend templates.
+var Backends = []string{"gorm", "sql", "sqlx", "pgx"}
+
+// templateData is what the querier templates are executed with.
+type templateData struct {
+	Package   string
+	Interface string
+	Imports   []string // quoted import paths, possibly with a name; "" separates groups
+	Methods   []InterfaceMethod
+	Groups    []Group  // set with Options.Split
+	Embeds    []string // receiver types besides querier, for a user "backend" to embed
+}
+
+// Group is the sub-interface for the methods of one querier file.
+type Group struct {
+	Name    string // e.g. UserQuerier for querier_user.go
+	File    string
+	Methods []InterfaceMethod
+}
+
+// groupName turns querier_user_profile.go into UserProfile+iface.
+func groupName(file, iface string) (string, error) {
+	stem := strings.TrimSuffix(strings.TrimPrefix(file, "querier_"), ".go")
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(stem, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	name := b.String() + iface
+	if !token.IsIdentifier(name) || name == iface {
+		return "", fmt.Errorf("generator: %s: cannot derive an interface name from the file name", file)
+	}
+	return name, nil
+}
+
+// groupMethods splits the sorted methods by file, in file order.
+func groupMethods(iface string, methods []InterfaceMethod) ([]Group, error) {
+	var groups []Group
+	index := map[string]int{}
+	owner := map[string]string{}
+	for _, m := range methods {
+		file := filepath.Base(m.pos.Filename)
+		i, ok := index[file]
+		if !ok {
+			name, err := groupName(file, iface)
+			if err != nil {
+				return nil, err
+			}
+			if prev, dup := owner[name]; dup {
+				return nil, fmt.Errorf("generator: %s and %s both map to interface %s", prev, file, name)
+			}
+			owner[name] = file
+			i = len(groups)
+			index[file] = i
+			groups = append(groups, Group{Name: name, File: file})
+		}
+		groups[i].Methods = append(groups[i].Methods, m)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].File < groups[j].File })
+	return groups, nil
+}
+
+// DocLines returns the doc comment split into lines, for templates.
+func (m InterfaceMethod) DocLines() []string {
+	if m.Doc == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(m.Doc, "\n"), "\n")
+}
+
+// loadTemplates parses the shared templates, the backend's, the wrapper
+// templates and then the user's file, which may redefine any of "file",
+// "header", "imports", "interface", "backend", "mock", "logging" or "retry".
+func loadTemplates(backend, userFile string) (*template.Template, error) {
+	known := false
+	for _, b := range Backends {
+		known = known || b == backend
+	}
+	if !known {
+		return nil, fmt.Errorf("generator: unknown backend %q (want one of %s)", backend, strings.Join(Backends, ", "))
+	}
+	t, err := template.New("file").ParseFS(builtinTemplates,
+		"templates/common.tmpl", "templates/savepoint.tmpl", "templates/"+backend+".tmpl",
+		"templates/mock.tmpl", "templates/logging.tmpl", "templates/retry.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if userFile != "" {
+		b, err := os.ReadFile(userFile)
+		if err != nil {
+			return nil, err
+		}
+		if t, err = t.New(userFile).Parse(string(b)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// File is one generated file.
+type File struct {
+	Name    string // relative to Options.Dir
+	Content []byte
+}
+
+// extraFiles maps the optional outputs to their template and file prefix.
+func extraFiles(opts Options) []struct{ tmpl, name string } {
+	var out []struct{ tmpl, name string }
+	add := func(on bool, tmpl string) {
+		if on {
+			out = append(out, struct{ tmpl, name string }{tmpl, tmpl + "_" + opts.Output})
+		}
+	}
+	add(opts.Mock, "mock")
+	add(opts.Logging, "logging")
+	add(opts.Retry, "retry")
+	return out
+}
+
+func renderQuerierFiles(opts Options, imports []string, methods []InterfaceMethod) ([]File, error) {
+	t, err := loadTemplates(opts.Backend, opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort methods alphabetically
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+
+	data := templateData{
+		Package:   opts.Package,
+		Interface: opts.Interface,
+		Methods:   methods,
+	}
+	for _, r := range opts.Receivers {
+		if r != "querier" && slices.ContainsFunc(methods, func(m InterfaceMethod) bool { return m.recv == r }) {
+			data.Embeds = append(data.Embeds, r)
+		}
+	}
+	// The built-in backends only know how to build querier; an embedded
+	// receiver would be zero-valued and outside WithTx.
+	if len(data.Embeds) > 0 && t.Lookup("backend").Tree.ParseName != opts.Template {
+		for _, m := range methods {
+			if m.recv == data.Embeds[0] {
+				return nil, fmt.Errorf("%s: method %s has receiver %s, which the built-in %s backend cannot construct; redefine \"backend\" in a template to wire it up",
+					m.pos, m.Name, m.recv, opts.Backend)
+			}
+		}
+	}
+	if opts.Split {
+		if data.Groups, err = groupMethods(opts.Interface, methods); err != nil {
+			return nil, err
+		}
+	}
+	todo := append([]struct{ tmpl, name string }{{"file", opts.Output}}, extraFiles(opts)...)
+	files := make([]File, 0, len(todo))
+	for _, f := range todo {
+		uses := f.tmpl
+		if uses == "file" {
+			uses = opts.Backend
+		}
+		data.Imports = importLines(imports, quoted(builtinImports[uses]))
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, f.tmpl, data); err != nil {
+			return nil, err
+		}
+		out, err := fixImports(filepath.Join(opts.Dir, f.name), buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("generator: format %s: %w", f.name, err)
+		}
+		files = append(files, File{Name: f.name, Content: out})
+	}
+	return files, nil
+}
@@ -0,0 +1,11 @@
+package archive
+
+import "context"
+
+type querier struct{}
+
+// ArchiveOrders belongs to another package and must stay out of store's
+// Querier.
+func (q *querier) ArchiveOrders(ctx context.Context) error {
+	return nil
+}
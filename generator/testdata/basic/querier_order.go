@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	uuidpkg "github.com/google/uuid"
+)
+
+type Order struct {
+	ID      int64
+	UserID  uuidpkg.UUID
+	Created time.Time
+}
+
+func (q *querier) CreateOrder(ctx context.Context, o *Order) error {
+	return nil
+}
+
+func (q *querier) OrdersSince(ctx context.Context, since time.Time) (orders []Order, err error) {
+	return
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"context"
+
+	uuidpkg "github.com/google/uuid"
+)
+
+type User struct {
+	ID   uuidpkg.UUID
+	Name string
+}
+
+// GetUser loads a user by id.
+func (q *querier) GetUser(ctx context.Context, id uuidpkg.UUID) (*User, error) {
+	return nil, nil
+}
+
+func (q *querier) ListUsers(ctx context.Context, ids ...uuidpkg.UUID) ([]User, error) {
+	return nil, nil
+}
+
+// helper stays out of the interface.
+//
+//querier:ignore
+func (q *querier) helper() {}
@@ -0,0 +1,20 @@
+package store
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type reports struct {
+	db *gorm.DB
+}
+
+// DailyTotals sums the orders of the last days.
+func (r reports) DailyTotals(ctx context.Context, days int) (map[string]int64, error) {
+	return nil, nil
+}
+
+func (r *reports) TopCustomers(ctx context.Context, n int) ([]string, error) {
+	return nil, nil
+}
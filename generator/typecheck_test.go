@@ -0,0 +1,231 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubs declare what the generated code uses of the backends' third-party
+// packages, with the upstream signatures.
+var stubs = map[string]string{
+	"go.uber.org/zap": `package zap
+
+import "time"
+
+type Field struct{}
+
+type Logger struct{}
+
+func (log *Logger) Debug(msg string, fields ...Field) {}
+func (log *Logger) Warn(msg string, fields ...Field)  {}
+
+func String(key string, val string) Field          { return Field{} }
+func Duration(key string, val time.Duration) Field { return Field{} }
+func Error(err error) Field                        { return Field{} }
+`,
+	"gorm.io/gorm": `package gorm
+
+import (
+	"context"
+	"database/sql"
+)
+
+type DB struct{}
+
+func (db *DB) WithContext(ctx context.Context) *DB                                { return db }
+func (db *DB) Transaction(fc func(tx *DB) error, opts ...*sql.TxOptions) (err error) { return nil }
+`,
+	"github.com/jmoiron/sqlx": `package sqlx
+
+import (
+	"context"
+	"database/sql"
+)
+
+type DB struct{ *sql.DB }
+
+type Tx struct{ *sql.Tx }
+
+type Rows struct{ *sql.Rows }
+
+type Row struct{}
+
+type binder interface {
+	DriverName() string
+	Rebind(string) string
+	BindNamed(string, interface{}) (string, []interface{}, error)
+}
+
+type QueryerContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*Rows, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *Row
+}
+
+type ExecerContext interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type ExtContext interface {
+	binder
+	QueryerContext
+	ExecerContext
+}
+
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) { return nil, nil }
+
+func (db *DB) DriverName() string                                              { return "" }
+func (db *DB) Rebind(query string) string                                      { return query }
+func (db *DB) BindNamed(query string, arg interface{}) (string, []interface{}, error) { return query, nil, nil }
+func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) { return nil, nil }
+func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *Row { return nil }
+
+func (tx *Tx) DriverName() string                                              { return "" }
+func (tx *Tx) Rebind(query string) string                                      { return query }
+func (tx *Tx) BindNamed(query string, arg interface{}) (string, []interface{}, error) { return query, nil, nil }
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) { return nil, nil }
+func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *Row { return nil }
+`,
+	"github.com/jackc/pgx/v5": `package pgx
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type Rows interface{ Close() }
+
+type Row interface{ Scan(dest ...any) error }
+
+type Tx interface {
+	Begin(ctx context.Context) (Tx, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+	Exec(ctx context.Context, sql string, arguments ...any) (commandTag pgconn.CommandTag, err error)
+	Query(ctx context.Context, sql string, args ...any) (Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) Row
+}
+`,
+	"github.com/jackc/pgx/v5/pgconn": `package pgconn
+
+type CommandTag struct{ s string }
+`,
+}
+
+// stubImporter serves stubs and imports everything else from source.
+type stubImporter struct {
+	fset *token.FileSet
+	src  types.ImporterFrom
+	pkgs map[string]*types.Package
+}
+
+func newStubImporter(fset *token.FileSet) *stubImporter {
+	return &stubImporter{fset: fset, src: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom), pkgs: map[string]*types.Package{}}
+}
+
+func (im *stubImporter) Import(path string) (*types.Package, error) {
+	return im.ImportFrom(path, "", 0)
+}
+
+func (im *stubImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if pkg, ok := im.pkgs[path]; ok {
+		return pkg, nil
+	}
+	src, ok := stubs[path]
+	if !ok {
+		return im.src.ImportFrom(path, dir, mode)
+	}
+	f, err := parser.ParseFile(im.fset, path+"/stub.go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := (&types.Config{Importer: im}).Check(path, im.fset, []*ast.File{f}, nil)
+	if err != nil {
+		return nil, err
+	}
+	im.pkgs[path] = pkg
+	return pkg, nil
+}
+
+// typeCheck type-checks the generated files together with the other .go
+// files of dir, as one package.
+func typeCheck(im *stubImporter, dir string, generated []File) error {
+	sources := map[string][]byte{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".go") && !strings.HasSuffix(e.Name(), "_test.go") {
+			if sources[e.Name()], err = os.ReadFile(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range generated {
+		sources[f.Name] = f.Content
+	}
+	var files []*ast.File
+	for name, src := range sources {
+		f, err := parser.ParseFile(im.fset, filepath.Join(dir, name), src, 0)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	var errs []string
+	conf := types.Config{Importer: im, Error: func(err error) { errs = append(errs, err.Error()) }}
+	conf.Check(files[0].Name.Name, im.fset, files, nil)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func TestGenerateBackendsTypeCheck(t *testing.T) {
+	dir := typesDir(t)
+	im := newStubImporter(token.NewFileSet())
+	for _, b := range Backends {
+		files, err := GenerateFiles(Options{Package: "store", Dir: dir, Backend: b, Mock: true, Logging: true, Retry: true})
+		if err != nil {
+			t.Fatalf("%s: GenerateFiles: %v", b, err)
+		}
+		if err := typeCheck(im, dir, files); err != nil {
+			t.Fatalf("%s: generated code does not type-check:\n%v", b, err)
+		}
+	}
+}
+
+func TestGenerateSplitTypeCheck(t *testing.T) {
+	dir := filepath.Join("testdata", "split")
+	im := newStubImporter(token.NewFileSet())
+	for _, b := range Backends {
+		opts := Options{Package: "store", Dir: dir, Backend: b, Split: true, Mock: true, Logging: true, Retry: true}
+		files, err := GenerateFiles(opts)
+		if err != nil {
+			t.Fatalf("%s: GenerateFiles: %v", b, err)
+		}
+		if err := typeCheck(im, dir, files); err != nil {
+			t.Fatalf("%s: generated code does not type-check:\n%v", b, err)
+		}
+	}
+
+	opts := Options{Package: "store", Dir: dir, Template: filepath.Join(dir, "backend.tmpl"), Receivers: []string{"querier", "reports"},
+		Split: true, Mock: true, Logging: true, Retry: true}
+	files, err := GenerateFiles(opts)
+	if err != nil {
+		t.Fatalf("receivers: GenerateFiles: %v", err)
+	}
+	if err := typeCheck(im, dir, files); err != nil {
+		t.Fatalf("receivers: generated code does not type-check:\n%v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bronystylecrazy/gx"
+)
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	Options
+
+	Debounce  time.Duration // quiet period after the last change, default 200ms
+	Poll      time.Duration // polling interval without notifications, default 500ms
+	ForcePoll bool          // poll even where notifications are available
+	Out       io.Writer     // progress and method diffs, default os.Stdout
+}
+
+func (o WatchOptions) withDefaults() WatchOptions {
+	o.Options = o.Options.withDefaults()
+	if o.Debounce <= 0 {
+		o.Debounce = 200 * time.Millisecond
+	}
+	if o.Poll <= 0 {
+		o.Poll = 500 * time.Millisecond
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	return o
+}
+
+// watcher reports that files matched by a watchTargets may have changed.
+type watcher interface {
+	Changes() <-chan struct{}
+	Errors() <-chan error
+	Close() error
+}
+
+// isQuerierFile matches the names findQuerierFiles picks up.
+func isQuerierFile(name, output string) bool {
+	return strings.HasPrefix(name, "querier_") && strings.HasSuffix(name, ".go") &&
+		!strings.HasSuffix(name, "_test.go") && name != output
+}
+
+// watchTargets is what Watch reacts to: the querier files in dir, plus the
+// generated files and the template file, which can be edited or deleted
+// behind its back.
+type watchTargets struct {
+	dir, output string
+	files       map[string]bool // cleaned paths
+}
+
+func newWatchTargets(opts Options) watchTargets {
+	t := watchTargets{dir: opts.Dir, output: opts.Output, files: map[string]bool{}}
+	t.files[filepath.Join(opts.Dir, opts.Output)] = true
+	for _, f := range extraFiles(opts) {
+		t.files[filepath.Join(opts.Dir, f.name)] = true
+	}
+	if opts.Template != "" {
+		t.files[filepath.Clean(opts.Template)] = true
+	}
+	return t
+}
+
+// dirs lists the directories to watch, dir first.
+func (t watchTargets) dirs() []string {
+	out := []string{t.dir}
+	for _, f := range slices.Sorted(maps.Keys(t.files)) {
+		if d := filepath.Dir(f); !slices.Contains(out, d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// match reports whether the entry name in dir is a target.
+func (t watchTargets) match(dir, name string) bool {
+	return dir == t.dir && isQuerierFile(name, t.output) || t.files[filepath.Join(dir, name)]
+}
+
+// methodSet is what the generated files depend on: every method's rendered
+// form keyed by name, plus the imports and the template file. Edits to
+// method bodies leave it alone.
+type methodSet struct {
+	methods  map[string]string
+	imports  string
+	template string
+}
+
+func readMethodSet(opts Options) (methodSet, error) {
+	files, err := findQuerierFiles(opts.Dir, opts.Output)
+	if err != nil {
+		return methodSet{}, err
+	}
+	methods, imports, err := collectQuerier(files, opts)
+	if err != nil {
+		return methodSet{}, err
+	}
+	set := methodSet{methods: map[string]string{}, imports: strings.Join(imports, "\n")}
+	if opts.Template != "" {
+		b, err := os.ReadFile(opts.Template)
+		if err != nil {
+			return methodSet{}, fmt.Errorf("generator: template: %w", err)
+		}
+		set.template = string(b)
+	}
+	for _, m := range methods {
+		// Everything the templates use of a method, the signature last.
+		key := m.recv + "\n" + m.Doc + m.Signature()
+		if m.Retry {
+			key = retryDirective + "\n" + key
+		}
+		if opts.Split {
+			key = filepath.Base(m.pos.Filename) + "\n" + key
+		}
+		set.methods[m.Name] = key
+	}
+	return set, nil
+}
+
+func (s methodSet) equal(o methodSet) bool {
+	return s.imports == o.imports && s.template == o.template && maps.Equal(s.methods, o.methods)
+}
+
+// diff lists added (+), removed (-) and changed (~) methods by name, showing
+// the current signature.
+func (s methodSet) diff(prev methodSet) []string {
+	sig := func(key string) string {
+		lines := strings.Split(key, "\n")
+		return lines[len(lines)-1]
+	}
+	var out []string
+	for _, name := range slices.Sorted(maps.Keys(prev.methods)) {
+		if _, ok := s.methods[name]; !ok {
+			out = append(out, "- "+sig(prev.methods[name]))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.methods)) {
+		old, ok := prev.methods[name]
+		switch {
+		case !ok:
+			out = append(out, "+ "+sig(s.methods[name]))
+		case old != s.methods[name]:
+			out = append(out, "~ "+sig(s.methods[name]))
+		}
+	}
+	if s.imports != prev.imports {
+		out = append(out, "~ imports")
+	}
+	if s.template != prev.template {
+		out = append(out, "~ template")
+	}
+	return out
+}
+
+// generateFiles is GenerateFiles, wrapped by tests to count regenerations.
+var generateFiles = GenerateFiles
+
+// Watch regenerates the files for opts whenever the method set of the
+// querier files or the template changes, and rewrites generated files that
+// were edited or deleted, until ctx is done. It uses filesystem
+// notifications where available and polls otherwise; bursts of saves are
+// debounced. Parse errors are reported to Out and watching goes on.
+func Watch(ctx context.Context, opts WatchOptions) error {
+	opts = opts.withDefaults()
+	if opts.Package == "" {
+		return fmt.Errorf("generator: package name is required")
+	}
+
+	targets := newWatchTargets(opts.Options)
+	var w watcher
+	if !opts.ForcePoll {
+		nw, err := newNotifyWatcher(targets)
+		if err != nil {
+			fmt.Fprintf(opts.Out, "querier-gen: %v; polling every %v\n", err, opts.Poll)
+		} else {
+			w = nw
+		}
+	}
+	if w == nil {
+		w = newPollWatcher(targets, opts.Poll)
+	}
+	defer w.Close()
+
+	regen := make(chan struct{}, 1)
+	d, err := gx.NewDebouncer(ctx, gx.DebounceOpts[struct{}]{Wait: opts.Debounce}, func(struct{}) {
+		select {
+		case regen <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer d.Stop()
+
+	var prev *methodSet
+	var last []File
+	step := func() {
+		set, err := readMethodSet(opts.Options)
+		if err != nil {
+			fmt.Fprintf(opts.Out, "querier-gen: %v\n", err)
+			return
+		}
+		if prev == nil || !set.equal(*prev) {
+			files, err := generateFiles(opts.Options)
+			if err != nil {
+				fmt.Fprintf(opts.Out, "querier-gen: %v\n", err)
+				return
+			}
+			if prev != nil {
+				for _, line := range set.diff(*prev) {
+					fmt.Fprintf(opts.Out, "  %s\n", line)
+				}
+			}
+			prev, last = &set, files
+		}
+		// The method set alone misses generated files edited or deleted
+		// since the last run, so compare every one with the disk.
+		for _, f := range last {
+			path := filepath.Join(opts.Dir, f.Name)
+			if cur, err := os.ReadFile(path); err == nil && string(cur) == string(f.Content) {
+				continue
+			}
+			if err := os.WriteFile(path, f.Content, 0o644); err != nil {
+				fmt.Fprintf(opts.Out, "querier-gen: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(opts.Out, "querier-gen: wrote %s\n", path)
+		}
+	}
+
+	step()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.Changes():
+			d.Trigger(struct{}{})
+		case <-regen:
+			step()
+		case err := <-w.Errors():
+			return err
+		}
+	}
+}
+
+// WatchQuerier is GenerateQuerier in watch mode: it keeps
+// dir/querierFileName up to date until ctx is done.
+func WatchQuerier(ctx context.Context, packageName, dir, querierFileName string) error {
+	return Watch(ctx, WatchOptions{Options: Options{Package: packageName, Dir: dir, Output: querierFileName}})
+}
+
+// pollWatcher compares the size and mtime of the target files every tick.
+type pollWatcher struct {
+	changes chan struct{}
+	errs    chan error
+	done    chan struct{}
+}
+
+func newPollWatcher(targets watchTargets, every time.Duration) *pollWatcher {
+	w := &pollWatcher{changes: make(chan struct{}, 1), errs: make(chan error, 1), done: make(chan struct{})}
+	snapshot := func() string {
+		var b strings.Builder
+		for _, dir := range targets.dirs() {
+			entries, _ := os.ReadDir(dir)
+			for _, e := range entries {
+				if !targets.match(dir, e.Name()) {
+					continue
+				}
+				if info, err := e.Info(); err == nil {
+					fmt.Fprintf(&b, "%s %d %d\n", filepath.Join(dir, e.Name()), info.Size(), info.ModTime().UnixNano())
+				}
+			}
+		}
+		return b.String()
+	}
+	go func() {
+		last := snapshot()
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-t.C:
+				if cur := snapshot(); cur != last {
+					last = cur
+					select {
+					case w.changes <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return w
+}
+
+func (w *pollWatcher) Changes() <-chan struct{} { return w.changes }
+func (w *pollWatcher) Errors() <-chan error     { return w.errs }
+
+func (w *pollWatcher) Close() error {
+	close(w.done)
+	return nil
+}
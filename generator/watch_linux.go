@@ -0,0 +1,86 @@
+//go:build linux
+
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyWatcher watches the target directories with inotify. The fd is non-blocking and
+// wrapped in an *os.File, so reads park in the runtime poller and Close
+// unblocks them.
+type inotifyWatcher struct {
+	f       *os.File
+	targets watchTargets
+	dirs    map[int32]string // watch descriptor to directory
+	changes chan struct{}
+	errs    chan error
+}
+
+func newNotifyWatcher(targets watchTargets) (watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("generator: inotify: %w", err)
+	}
+	const mask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+	dirs := map[int32]string{}
+	for _, dir := range targets.dirs() {
+		wd, err := unix.InotifyAddWatch(fd, dir, mask)
+		if err != nil {
+			unix.Close(fd)
+			return nil, fmt.Errorf("generator: inotify %s: %w", dir, err)
+		}
+		dirs[int32(wd)] = dir
+	}
+	w := &inotifyWatcher{
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		targets: targets,
+		dirs:    dirs,
+		changes: make(chan struct{}, 1),
+		errs:    make(chan error, 1),
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.errs <- fmt.Errorf("generator: inotify: %w", err)
+			}
+			return
+		}
+		changed := false
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			name := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(ev.Len)]
+			if i := bytes.IndexByte(name, 0); i >= 0 {
+				name = name[:i]
+			}
+			changed = changed || ev.Mask&unix.IN_Q_OVERFLOW != 0 || w.targets.match(w.dirs[ev.Wd], string(name))
+			off += unix.SizeofInotifyEvent + int(ev.Len)
+		}
+		if changed {
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (w *inotifyWatcher) Changes() <-chan struct{} { return w.changes }
+func (w *inotifyWatcher) Errors() <-chan error     { return w.errs }
+
+func (w *inotifyWatcher) Close() error {
+	return w.f.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package generator
+
+import "errors"
+
+// newNotifyWatcher has no implementation here yet, so Watch polls.
+func newNotifyWatcher(targets watchTargets) (watcher, error) {
+	return nil, errors.New("generator: file notifications are not supported on this platform")
+}
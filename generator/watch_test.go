@@ -0,0 +1,197 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// countGenerations counts calls to generateFiles for the rest of the test.
+func countGenerations(t *testing.T) *atomic.Int32 {
+	var n atomic.Int32
+	generateFiles = func(opts Options) ([]File, error) {
+		n.Add(1)
+		return GenerateFiles(opts)
+	}
+	t.Cleanup(func() { generateFiles = GenerateFiles })
+	return &n
+}
+
+// startWatch runs Watch on opts with short timings; stop ends it and fails
+// the test if Watch returned an error.
+func startWatch(t *testing.T, opts Options, poll bool, out io.Writer) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, WatchOptions{
+			Options:   opts,
+			Debounce:  20 * time.Millisecond,
+			Poll:      20 * time.Millisecond,
+			ForcePoll: poll,
+			Out:       out,
+		})
+	}()
+	return func() {
+		t.Helper()
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("poll=%v: Watch: %v", poll, err)
+		}
+	}
+}
+
+// readFile returns the contents of path, or "" if it cannot be read.
+func readFile(path string) string {
+	b, _ := os.ReadFile(path)
+	return string(b)
+}
+
+func TestWatch(t *testing.T) {
+	generated := countGenerations(t)
+
+	for _, poll := range []bool{false, true} {
+		generated.Store(0)
+		dir := t.TempDir()
+		src := filepath.Join(dir, "querier_user.go")
+		write := func(body string) {
+			if err := os.WriteFile(src, []byte("package store\n\nimport \"context\"\n\n"+body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write("func (q *querier) Get(ctx context.Context) error { return nil }\n")
+
+		out := &syncBuffer{}
+		stop := startWatch(t, Options{Package: "store", Dir: dir}, poll, out)
+
+		target := filepath.Join(dir, "querier.go")
+		waitFor(t, "initial generation", func() bool { return strings.Contains(readFile(target), "Get(ctx context.Context) error") })
+
+		// A body-only edit keeps the method set, so nothing is regenerated;
+		// the next regeneration must be the one for the new method.
+		write("func (q *querier) Get(ctx context.Context) error { return ctx.Err() }\n")
+		time.Sleep(200 * time.Millisecond)
+		write("func (q *querier) Get(ctx context.Context) error { return nil }\n\nfunc (q *querier) Count(ctx context.Context) (int, error) { return 0, nil }\n")
+		waitFor(t, "regeneration", func() bool { return strings.Contains(readFile(target), "Count(ctx context.Context) (int, error)") })
+		waitFor(t, "diff", func() bool { return strings.Contains(out.String(), "+ Count(ctx context.Context) (int, error)") })
+		if n := generated.Load(); n != 2 {
+			t.Fatalf("poll=%v: generated %d times, want 2 (initial, new method):\n%s", poll, n, out)
+		}
+
+		stop()
+	}
+}
+
+func TestWatchRetryMarker(t *testing.T) {
+	generated := countGenerations(t)
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "querier_user.go")
+	write := func(doc string) {
+		body := "package store\n\nimport \"context\"\n\n" + doc + "func (q *querier) Get(ctx context.Context) error { return nil }\n"
+		if err := os.WriteFile(src, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("")
+
+	stop := startWatch(t, Options{Package: "store", Dir: dir, Retry: true}, true, &syncBuffer{})
+
+	target := filepath.Join(dir, "retry_querier.go")
+	waitFor(t, "initial generation", func() bool { return readFile(target) != "" })
+	unmarked := readFile(target)
+
+	// The marker is stripped from the doc text, but it changes the retry
+	// wrapper, so toggling it must regenerate.
+	write("//querier:retry\n")
+	waitFor(t, "regeneration on marking", func() bool { return generated.Load() == 2 && readFile(target) != unmarked })
+	write("")
+	waitFor(t, "regeneration on unmarking", func() bool { return generated.Load() == 3 && readFile(target) == unmarked })
+
+	stop()
+}
+
+func TestWatchOutputAndTemplate(t *testing.T) {
+	generated := countGenerations(t)
+
+	for _, poll := range []bool{false, true} {
+		generated.Store(0)
+		dir := t.TempDir()
+		src := []byte("package store\n\nimport \"context\"\n\nfunc (q *querier) Get(ctx context.Context) error { return nil }\n")
+		if err := os.WriteFile(filepath.Join(dir, "querier_user.go"), src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// The template lives outside dir, so its directory is watched too.
+		tmpl := filepath.Join(t.TempDir(), "custom.tmpl")
+		writeTmpl := func(db string) {
+			body := "{{define \"backend\"}}\ntype querier struct{ db " + db + " }\n\ntype TxFunc func({{.Interface}}) error\n{{end}}"
+			if err := os.WriteFile(tmpl, []byte(body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeTmpl("*Store")
+
+		out := &syncBuffer{}
+		stop := startWatch(t, Options{Package: "store", Dir: dir, Template: tmpl}, poll, out)
+
+		target := filepath.Join(dir, "querier.go")
+		waitFor(t, "initial generation", func() bool { return strings.Contains(readFile(target), "db *Store") })
+		want := readFile(target)
+		// Let the poller see the initial write before the file goes away.
+		time.Sleep(100 * time.Millisecond)
+
+		// Deleted or hand-edited output is restored without regenerating.
+		if err := os.Remove(target); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "restore after delete", func() bool { return readFile(target) == want })
+		if err := os.WriteFile(target, []byte("package store\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "restore after edit", func() bool { return readFile(target) == want })
+		if n := generated.Load(); n != 1 {
+			t.Fatalf("poll=%v: generated %d times, want 1:\n%s", poll, n, out)
+		}
+
+		// A template edit keeps the method set but changes the output.
+		writeTmpl("*DB")
+		waitFor(t, "regeneration on template edit", func() bool { return strings.Contains(readFile(target), "db *DB") })
+		waitFor(t, "diff", func() bool { return strings.Contains(out.String(), "~ template") })
+
+		stop()
+	}
+}
@@ -0,0 +1,260 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// The wrapper behaviour test generates the mock and retry files for a fake
+// querier and runs wrappersTest against them with go test. The fake fails
+// its first q.fails calls with q.err and records every call.
+const (
+	wrappersQuerier = `package store
+
+import "context"
+
+//querier:retry
+func (q *querier) Get(ctx context.Context, id int) (string, error) {
+	if err := q.call("Get"); err != nil {
+		return "", err
+	}
+	return "user", nil
+}
+
+func (q *querier) Insert(ctx context.Context, name string) error {
+	return q.call("Insert")
+}
+
+func (q *querier) Count() int { return len(q.calls) }
+`
+	wrappersFake = `package store
+
+import "context"
+
+type querier struct {
+	fails int
+	err   error
+	calls []string
+}
+
+func (q *querier) call(method string) error {
+	q.calls = append(q.calls, method)
+	if q.fails > 0 {
+		q.fails--
+		return q.err
+	}
+	return nil
+}
+
+func (q *querier) WithTx(fn TxFunc) error {
+	return q.WithTxContext(context.Background(), fn)
+}
+
+func (q *querier) WithTxContext(ctx context.Context, fn TxFunc) error {
+	if err := q.call("WithTx"); err != nil {
+		return err
+	}
+	return fn(q)
+}
+`
+	wrappersBackend = `{{define "backend"}}
+type TxFunc func({{.Interface}}) error
+{{end}}
+`
+	wrappersTest = `package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	q := &querier{fails: 2, err: driver.ErrBadConn}
+	r := NewRetryQuerier(q, RetryPolicy{Backoff: time.Millisecond})
+	if s, err := r.Get(ctx, 1); err != nil || s != "user" || len(q.calls) != 3 {
+		t.Fatalf("marked method: %q %v after %d calls", s, err, len(q.calls))
+	}
+
+	*q = querier{fails: 2, err: driver.ErrBadConn}
+	if err := r.Insert(ctx, "a"); err != driver.ErrBadConn || len(q.calls) != 1 {
+		t.Fatalf("unmarked method: %v after %d calls", err, len(q.calls))
+	}
+
+	*q = querier{fails: 5, err: driver.ErrBadConn}
+	if _, err := r.Get(ctx, 1); err != driver.ErrBadConn || len(q.calls) != 3 {
+		t.Fatalf("attempts: %v after %d calls", err, len(q.calls))
+	}
+
+	*q = querier{fails: 2, err: fmt.Errorf("query: %w", context.DeadlineExceeded)}
+	if _, err := r.Get(ctx, 1); err == nil || len(q.calls) != 1 {
+		t.Fatalf("deadline: %v after %d calls", err, len(q.calls))
+	}
+
+	*q = querier{fails: 1, err: driver.ErrBadConn}
+	if err := r.WithTx(func(Querier) error { return nil }); err != driver.ErrBadConn || len(q.calls) != 1 {
+		t.Fatalf("transaction: %v after %d calls", err, len(q.calls))
+	}
+	*q = querier{fails: 1, err: driver.ErrBadConn}
+	rt := NewRetryQuerier(q, RetryPolicy{Backoff: time.Millisecond, Transactions: true})
+	if err := rt.WithTx(func(Querier) error { return nil }); err != nil || len(q.calls) != 2 {
+		t.Fatalf("transaction with Transactions: %v after %d calls", err, len(q.calls))
+	}
+}
+
+func TestMock(t *testing.T) {
+	ctx := context.Background()
+	m := &MockQuerier{GetFunc: func(ctx context.Context, id int) (string, error) { return fmt.Sprint(id), nil }}
+	if s, err := m.Get(ctx, 7); s != "7" || err != nil {
+		t.Fatalf("Get: %q %v", s, err)
+	}
+	if err := m.Insert(ctx, "a"); err != nil {
+		t.Fatalf("unset Insert: %v", err)
+	}
+	if n := m.Count(); n != 0 {
+		t.Fatalf("unset Count: %d", n)
+	}
+	if err := m.WithTx(func(tx Querier) error { return tx.Insert(ctx, "b") }); err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	var got []string
+	for _, c := range m.Calls() {
+		got = append(got, fmt.Sprint(c.Method, len(c.Args)))
+	}
+	if want := "[Get2 Insert2 Count0 WithTx1 Insert2]"; fmt.Sprint(got) != want {
+		t.Fatalf("calls %v, want %s", got, want)
+	}
+	if m.Calls()[0].Args[1] != 7 {
+		t.Fatalf("Get args: %v", m.Calls()[0].Args)
+	}
+	if in := m.CallsTo("Insert"); len(in) != 2 || in[1].Args[1] != "b" {
+		t.Fatalf("CallsTo: %v", in)
+	}
+}
+`
+)
+
+// goTestGenerated writes files to a temp module named example.com/store,
+// generates into it with opts, Dir and Template set to the module and its
+// backend.tmpl, and runs go test there.
+func goTestGenerated(t *testing.T, files map[string]string, opts Options) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("runs go test on generated code")
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/store\n\ngo 1.25\n"
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts.Dir, opts.Template = dir, filepath.Join(dir, "backend.tmpl")
+	out, err := GenerateFiles(opts)
+	if err != nil {
+		t.Fatalf("GenerateFiles: %v", err)
+	}
+	for _, f := range out {
+		if err := os.WriteFile(filepath.Join(dir, f.Name), f.Content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Dir = dir
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test on generated code: %v\n%s", err, b)
+	}
+}
+
+func TestGeneratedWrappersBehave(t *testing.T) {
+	goTestGenerated(t, map[string]string{
+		"querier_store.go": wrappersQuerier,
+		"fake.go":          wrappersFake,
+		"store_test.go":    wrappersTest,
+		"backend.tmpl":     wrappersBackend,
+	}, Options{Package: "store", Mock: true, Retry: true})
+}
+
+// The receivers test wires an extra receiver type into querier with a user
+// backend template and calls its methods through NewQuerier, in and outside
+// a transaction.
+const (
+	receiversQuerier = `package store
+
+import "context"
+
+type reports struct {
+	db *db
+}
+
+func (r reports) Total(ctx context.Context) (string, error) { return r.db.name, nil }
+
+func (r *reports) Top(ctx context.Context) (string, error) { return r.db.name, nil }
+`
+	receiversBackend = `{{define "backend"}}
+type db struct{ name string }
+
+func New{{.Interface}}(d *db) {{.Interface}} {
+	return &querier{reports: reports{db: d}, db: d}
+}
+
+type querier struct {
+{{- template "embeds" .}}
+	db *db
+}
+
+type TxFunc func({{.Interface}}) error
+
+func (q *querier) WithTx(fn TxFunc) error {
+	return q.WithTxContext(context.Background(), fn)
+}
+
+func (q *querier) WithTxContext(ctx context.Context, fn TxFunc) error {
+	inTx := *q
+	inTx.db = &db{name: q.db.name + " tx"}
+	inTx.reports.db = inTx.db
+	return fn(&inTx)
+}
+{{end}}
+`
+	receiversTest = `package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReceivers(t *testing.T) {
+	ctx := context.Background()
+	q := NewQuerier(&db{name: "main"})
+	if s, err := q.Total(ctx); s != "main" || err != nil {
+		t.Fatalf("Total: %q %v", s, err)
+	}
+	if s, err := q.Top(ctx); s != "main" || err != nil {
+		t.Fatalf("Top: %q %v", s, err)
+	}
+	var total, top string
+	err := q.WithTx(func(tx Querier) error {
+		total, _ = tx.Total(ctx)
+		top, _ = tx.Top(ctx)
+		return nil
+	})
+	if err != nil || total != "main tx" || top != "main tx" {
+		t.Fatalf("in WithTx: Total %q, Top %q, %v", total, top, err)
+	}
+}
+`
+)
+
+func TestGeneratedReceiversBehave(t *testing.T) {
+	goTestGenerated(t, map[string]string{
+		"querier_reports.go": receiversQuerier,
+		"store_test.go":      receiversTest,
+		"backend.tmpl":       receiversBackend,
+	}, Options{Package: "store", Receivers: []string{"querier", "reports"}})
+}
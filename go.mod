@@ -9,6 +9,9 @@ require (
 	go.uber.org/fx v1.24.0
 	go.uber.org/zap v1.27.0
 	golang.org/x/exp v0.0.0-20250911091902-df9299821621
+	golang.org/x/sys v0.36.0
+	golang.org/x/tools v0.37.0
+	google.golang.org/protobuf v1.36.11
 )
 
 require (
@@ -22,5 +25,6 @@ require (
 	github.com/valyala/fasthttp v1.62.0 // indirect
 	go.uber.org/dig v1.19.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/mod v0.28.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
 )
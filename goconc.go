@@ -3,6 +3,7 @@ package gx
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"time"
 )
@@ -112,6 +113,288 @@ func (t *throttler) Stop() {
 	})
 }
 
+// ------------------------------------------------------------
+// PriorityThrottler (token bucket with per-class queues)
+// ------------------------------------------------------------
+
+type PriorityPolicy int
+
+const (
+	PriorityStrict       PriorityPolicy = iota // lower class index always wins
+	PriorityWeightedFair                       // share tokens by class weight (WFQ)
+)
+
+type PriorityThrottler interface {
+	Acquire(ctx context.Context, class int) error
+	TryAcquire(class int) bool
+	Stats(class int) PriorityClassStats
+	Stop()
+}
+
+type PriorityThrottlerOpts struct {
+	Interval time.Duration
+	Burst    int
+	Policy   PriorityPolicy
+	Classes  int    // number of classes; class 0 is the highest priority
+	Weights  []int  // optional per-class weights for PriorityWeightedFair (default 1)
+	OnStop   func() // optional
+}
+
+type PriorityClassStats struct {
+	Acquired  uint64        // tokens granted (Acquire + TryAcquire)
+	Rejected  uint64        // TryAcquire misses
+	Canceled  uint64        // Acquire calls abandoned by ctx or Stop
+	Waiting   int           // callers currently queued
+	TotalWait time.Duration // accumulated queueing time of granted Acquire calls
+}
+
+var (
+	errPriorityThrottlerStopped = errors.New("gx.PriorityThrottler: stopped")
+	errPriorityClassRange       = errors.New("gx.PriorityThrottler: class out of range")
+)
+
+func NewPriorityThrottler(ctx context.Context, opts PriorityThrottlerOpts) (PriorityThrottler, error) {
+	if opts.Interval <= 0 {
+		return nil, errors.New("gx.PriorityThrottler: Interval must be > 0")
+	}
+	if opts.Classes <= 0 {
+		opts.Classes = len(opts.Weights)
+	}
+	if opts.Classes <= 0 {
+		return nil, errors.New("gx.PriorityThrottler: Classes must be > 0")
+	}
+	if len(opts.Weights) > opts.Classes {
+		return nil, errors.New("gx.PriorityThrottler: more Weights than Classes")
+	}
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	t := &priorityThrottler{
+		interval: opts.Interval,
+		burst:    opts.Burst,
+		tokens:   opts.Burst, // warm bucket
+		policy:   opts.Policy,
+		classes:  make([]priorityClass, opts.Classes),
+		stop:     make(chan struct{}),
+		onStop:   opts.OnStop,
+	}
+	for i := range t.classes {
+		t.classes[i].weight = 1
+		if i < len(opts.Weights) {
+			if opts.Weights[i] <= 0 {
+				return nil, errors.New("gx.PriorityThrottler: Weights must be > 0")
+			}
+			t.classes[i].weight = opts.Weights[i]
+		}
+	}
+	go t.refill(ctx)
+	return t, nil
+}
+
+type priorityWaiter struct {
+	ready         chan struct{}
+	granted       bool
+	since         time.Time
+	start, finish float64 // WFQ virtual tags, assigned on enqueue
+}
+
+type priorityClass struct {
+	queue  []*priorityWaiter // FIFO
+	weight int
+	finish float64 // WFQ virtual finish tag of the last enqueued caller
+	stats  PriorityClassStats
+}
+
+type priorityThrottler struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	tokens   int // invariant: tokens > 0 only while no caller is queued
+	policy   PriorityPolicy
+	classes  []priorityClass
+	vtime    float64 // WFQ virtual clock
+	stopped  bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	onStop   func()
+}
+
+func (t *priorityThrottler) refill(ctx context.Context) {
+	tk := time.NewTicker(t.interval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.stop:
+			return
+		case <-tk.C:
+			t.mu.Lock()
+			t.releaseLocked()
+			t.mu.Unlock()
+		}
+	}
+}
+
+// releaseLocked hands one token to the next queued caller, or banks it.
+func (t *priorityThrottler) releaseLocked() {
+	if t.stopped {
+		return
+	}
+	if c := t.nextClassLocked(); c >= 0 {
+		t.grantLocked(c)
+		return
+	}
+	if t.tokens < t.burst {
+		t.tokens++
+	}
+}
+
+func (t *priorityThrottler) nextClassLocked() int {
+	best := -1
+	var bestFinish float64
+	for i := range t.classes {
+		pc := &t.classes[i]
+		if len(pc.queue) == 0 {
+			continue
+		}
+		if t.policy == PriorityStrict {
+			return i
+		}
+		if f := pc.queue[0].finish; best < 0 || f < bestFinish {
+			best, bestFinish = i, f
+		}
+	}
+	return best
+}
+
+func (t *priorityThrottler) grantLocked(class int) {
+	pc := &t.classes[class]
+	w := pc.queue[0]
+	pc.queue[0] = nil
+	pc.queue = pc.queue[1:]
+	t.vtime = w.start
+
+	w.granted = true
+	pc.stats.Acquired++
+	pc.stats.TotalWait += time.Since(w.since)
+	close(w.ready)
+}
+
+func (t *priorityThrottler) validClass(class int) bool {
+	return class >= 0 && class < len(t.classes)
+}
+
+// removeLocked drops an abandoned waiter and re-tags the ones queued behind
+// it, so the class doesn't keep the virtual time the waiter had claimed.
+func (t *priorityThrottler) removeLocked(pc *priorityClass, w *priorityWaiter) {
+	i := slices.Index(pc.queue, w)
+	if i < 0 {
+		return
+	}
+	pc.queue = slices.Delete(pc.queue, i, i+1)
+	prev := w.start
+	for _, q := range pc.queue[i:] {
+		q.start = max(prev, t.vtime)
+		q.finish = q.start + 1/float64(pc.weight)
+		prev = q.finish
+	}
+	if n := len(pc.queue); n > 0 {
+		pc.finish = pc.queue[n-1].finish
+	} else {
+		pc.finish = t.vtime
+	}
+}
+
+func (t *priorityThrottler) Acquire(ctx context.Context, class int) error {
+	if !t.validClass(class) {
+		return errPriorityClassRange
+	}
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return errPriorityThrottlerStopped
+	}
+	if err := ctx.Err(); err != nil {
+		t.classes[class].stats.Canceled++
+		t.mu.Unlock()
+		return err
+	}
+	if t.tokens > 0 {
+		t.tokens--
+		t.classes[class].stats.Acquired++
+		t.mu.Unlock()
+		return nil
+	}
+	pc := &t.classes[class]
+	w := &priorityWaiter{ready: make(chan struct{}), since: time.Now()}
+	// a newly backlogged class starts at the current virtual time so idle
+	// periods don't accumulate credit
+	w.start = max(pc.finish, t.vtime)
+	w.finish = w.start + 1/float64(pc.weight)
+	pc.finish = w.finish
+	pc.queue = append(pc.queue, w)
+	t.mu.Unlock()
+
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-t.stop:
+		err = errPriorityThrottlerStopped
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if w.granted {
+		// lost the race against a grant: honor it rather than drop the token
+		return nil
+	}
+	t.removeLocked(pc, w)
+	pc.stats.Canceled++
+	return err
+}
+
+func (t *priorityThrottler) TryAcquire(class int) bool {
+	if !t.validClass(class) {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped || t.tokens == 0 {
+		t.classes[class].stats.Rejected++
+		return false
+	}
+	t.tokens--
+	t.classes[class].stats.Acquired++
+	return true
+}
+
+func (t *priorityThrottler) Stats(class int) PriorityClassStats {
+	if !t.validClass(class) {
+		return PriorityClassStats{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.classes[class].stats
+	st.Waiting = len(t.classes[class].queue)
+	return st
+}
+
+func (t *priorityThrottler) Stop() {
+	t.stopOnce.Do(func() {
+		if t.onStop != nil {
+			t.onStop()
+		}
+		t.mu.Lock()
+		t.stopped = true
+		t.mu.Unlock()
+		close(t.stop)
+	})
+}
+
 // ------------------------------------------------------------
 // Debouncer (single-key)
 // ------------------------------------------------------------
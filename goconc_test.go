@@ -80,6 +80,179 @@ func TestThrottler_Acquire_Try_Stop(t *testing.T) {
 	}
 }
 
+// ------- PriorityThrottler -------
+
+// queueOrder drains a 1-token bucket, then queues callers class by class and
+// records the class of each grant in order.
+func queueOrder(t *testing.T, thr PriorityThrottler, classes []int) <-chan int {
+	t.Helper()
+	if !thr.TryAcquire(0) {
+		t.Fatal("expected warm token")
+	}
+	out := make(chan int, len(classes))
+	for _, c := range classes {
+		go func(c int) {
+			if err := thr.Acquire(context.Background(), c); err == nil {
+				out <- c
+			}
+		}(c)
+		// let the goroutine enqueue so FIFO order within a class is deterministic
+		time.Sleep(time.Millisecond)
+	}
+	return out
+}
+
+func TestPriorityThrottler_Strict(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	thr, err := NewPriorityThrottler(ctx, PriorityThrottlerOpts{
+		Interval: 40 * time.Millisecond,
+		Burst:    1,
+		Policy:   PriorityStrict,
+		Classes:  2,
+	})
+	if err != nil {
+		t.Fatalf("NewPriorityThrottler err: %v", err)
+	}
+	defer thr.Stop()
+
+	out := queueOrder(t, thr, []int{1, 1, 0})
+	for i, want := range []int{0, 1, 1} {
+		got, ok := recvWithin(t, out, 200*time.Millisecond)
+		if !ok || got != want {
+			t.Fatalf("grant %d: want class %d, got %d ok=%v", i, want, got, ok)
+		}
+	}
+	if st := thr.Stats(1); st.Acquired != 2 || st.Waiting != 0 {
+		t.Fatalf("unexpected low-class stats: %+v", st)
+	}
+	if thr.TryAcquire(1) {
+		t.Fatal("expected TryAcquire to miss on empty bucket")
+	}
+	if st := thr.Stats(1); st.Rejected != 1 {
+		t.Fatalf("expected one rejection, got %+v", st)
+	}
+}
+
+func TestPriorityThrottler_WeightedFair(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	thr, err := NewPriorityThrottler(ctx, PriorityThrottlerOpts{
+		Interval: 30 * time.Millisecond,
+		Burst:    1,
+		Policy:   PriorityWeightedFair,
+		Weights:  []int{3, 1},
+	})
+	if err != nil {
+		t.Fatalf("NewPriorityThrottler err: %v", err)
+	}
+	defer thr.Stop()
+
+	// low class queues first, yet with weights 3:1 the first four grants
+	// must split 3 to 1 in favor of class 0
+	out := queueOrder(t, thr, []int{1, 1, 1, 1, 0, 0, 0, 0})
+	counts := map[int]int{}
+	for i := 0; i < 4; i++ {
+		c, ok := recvWithin(t, out, 200*time.Millisecond)
+		if !ok {
+			t.Fatal("grant did not arrive")
+		}
+		counts[c]++
+	}
+	if counts[0] != 3 || counts[1] != 1 {
+		t.Fatalf("want 3:1 split, got %v", counts)
+	}
+
+	// a backlog of abandoned waiters must not push the class behind
+	thr2, err := NewPriorityThrottler(ctx, PriorityThrottlerOpts{
+		Interval: 30 * time.Millisecond,
+		Burst:    1,
+		Policy:   PriorityWeightedFair,
+		Weights:  []int{1, 1},
+	})
+	if err != nil {
+		t.Fatalf("NewPriorityThrottler err: %v", err)
+	}
+	defer thr2.Stop()
+	if !thr2.TryAcquire(0) {
+		t.Fatal("expected warm token")
+	}
+	cctx, ccancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = thr2.Acquire(cctx, 1)
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	ccancel()
+	wg.Wait()
+	if st := thr2.Stats(1); st.Waiting != 0 {
+		t.Fatalf("abandoned waiters still queued: %+v", st)
+	}
+	// let a token bank so queueOrder can drain it
+	sleepPad(30 * time.Millisecond)
+
+	out = queueOrder(t, thr2, []int{0, 0, 0, 0, 1, 1, 1, 1})
+	counts = map[int]int{}
+	for i := 0; i < 4; i++ {
+		c, ok := recvWithin(t, out, 200*time.Millisecond)
+		if !ok {
+			t.Fatal("grant did not arrive")
+		}
+		counts[c]++
+	}
+	if counts[0] != 2 || counts[1] != 2 {
+		t.Fatalf("want 2:2 split after cancellations, got %v", counts)
+	}
+}
+
+func TestPriorityThrottler_CancelAndStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	thr, err := NewPriorityThrottler(ctx, PriorityThrottlerOpts{
+		Interval: time.Hour,
+		Classes:  1,
+	})
+	if err != nil {
+		t.Fatalf("NewPriorityThrottler err: %v", err)
+	}
+	if err := thr.Acquire(ctx, 1); err == nil {
+		t.Fatal("expected error for out-of-range class")
+	}
+	if thr.TryAcquire(-1) {
+		t.Fatal("expected TryAcquire to reject out-of-range class")
+	}
+	if st := thr.Stats(1); st != (PriorityClassStats{}) {
+		t.Fatalf("expected zero stats for out-of-range class, got %+v", st)
+	}
+	if !thr.TryAcquire(0) {
+		t.Fatal("expected warm token")
+	}
+
+	wctx, wcancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer wcancel()
+	if err := thr.Acquire(wctx, 0); err == nil {
+		t.Fatal("expected ctx error")
+	}
+	if st := thr.Stats(0); st.Canceled != 1 || st.Waiting != 0 {
+		t.Fatalf("unexpected stats after cancel: %+v", st)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- thr.Acquire(ctx, 0) }()
+	time.Sleep(5 * time.Millisecond)
+	thr.Stop()
+	if err, ok := recvWithin(t, done, 50*time.Millisecond); !ok || err == nil {
+		t.Fatalf("expected Acquire to fail after Stop, got %v ok=%v", err, ok)
+	}
+}
+
 // ------- Debouncer (single) -------
 
 func TestDebouncer_Trailing_Emit(t *testing.T) {
@@ -1,37 +1,17 @@
 package gx
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
-	"sync/atomic"
-
-	"github.com/bronystylecrazy/gx/idcodec"
 )
 
 type ID uint64
 
-var defaultCodec atomic.Pointer[idcodec.Codec]
-
-func SetDefaultCodec(c *idcodec.Codec) {
-	if c == nil {
-		panic("gx: codec cannot be nil")
-	}
-	defaultCodec.Store(c)
-}
-
-func getCodec() (*idcodec.Codec, error) {
-	c := defaultCodec.Load()
-	if c == nil {
-		return nil, errors.New("gx: DefaultCodec is nil (call gx.SetDefaultCodec at boot)")
-	}
-	return c, nil
-}
-
 func NewID(u uint64) *ID    { x := ID(u); return &x }
 func (i ID) Uint64() uint64 { return uint64(i) }
 
@@ -40,81 +20,102 @@ func (i ID) MarshalJSON() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if a, ok := c.(idAppender); ok {
+		if b, ok := appendQuotedToken(a, uint64(i)); ok {
+			return b, nil
+		}
+	}
 	s := c.EncodeUint64(uint64(i))
 	return json.Marshal(s)
 }
 
+// idAppender is implemented by codecs that can encode without allocating
+// intermediate strings, such as *idcodec.Codec.
+type idAppender interface {
+	AppendEncode(dst []byte, id uint64) []byte
+}
+
+// appendQuotedToken builds the JSON string for id directly, reporting false
+// when the alphabet has chars json.Marshal would escape.
+func appendQuotedToken(a idAppender, id uint64) ([]byte, bool) {
+	b := make([]byte, 1, 32)
+	b[0] = '"'
+	b = a.AppendEncode(b, id)
+	for _, ch := range b[1:] {
+		if ch < 0x20 || ch >= 0x80 || ch == '"' || ch == '\\' || ch == '<' || ch == '>' || ch == '&' {
+			return nil, false
+		}
+	}
+	return append(b, '"'), true
+}
+
 func (i *ID) UnmarshalJSON(b []byte) error {
+	u, err := unmarshalIDJSON(b, decodeDefault)
+	if err != nil {
+		return err
+	}
+	*i = ID(u)
+	return nil
+}
+
+// unmarshalIDJSON accepts null, an encoded string (decoded with decode) or a
+// raw number; null and "" yield 0.
+func unmarshalIDJSON(b []byte, decode func(string) (uint64, error)) (uint64, error) {
 	if string(b) == "null" {
-		*i = 0
-		return nil
+		return 0, nil
 	}
 	var s string
 	if err := json.Unmarshal(b, &s); err == nil {
 		s = strings.TrimSpace(s)
 		if s == "" {
-			*i = 0
-			return nil
-		}
-		c, err := getCodec()
-		if err != nil {
-			return err
+			return 0, nil
 		}
-		u, err := c.DecodeToUint64(s)
+		u, err := decode(s)
 		if err != nil {
-			return fmt.Errorf("gx: invalid encoded ID: %w", err)
+			if errors.Is(err, errNoDefaultCodec) {
+				return 0, err
+			}
+			return 0, fmt.Errorf("gx: invalid encoded ID: %w", err)
 		}
-		*i = ID(u)
-		return nil
+		return u, nil
 	}
 	var n uint64
 	if err := json.Unmarshal(b, &n); err == nil {
-		*i = ID(n)
-		return nil
+		return n, nil
 	}
-	return errors.New("gx: id must be string (encoded) or number")
+	return 0, errors.New("gx: id must be string (encoded) or number")
 }
 
 func (i ID) Value() (driver.Value, error) {
-	return int64(i), nil
+	return idValue(uint64(i), func() (string, error) {
+		c, err := getCodec()
+		if err != nil {
+			return "", err
+		}
+		return c.EncodeUint64(uint64(i)), nil
+	})
 }
 
 func (i *ID) Scan(src any) error {
-	switch v := src.(type) {
-	case int64:
-		*i = ID(uint64(v))
-		return nil
-	case []byte:
-		u, err := strconv.ParseUint(string(v), 10, 64)
-		if err != nil {
-			return fmt.Errorf("gx: scan []byte: %w", err)
-		}
-		*i = ID(u)
-		return nil
-	case string:
-		u, err := strconv.ParseUint(v, 10, 64)
-		if err != nil {
-			return fmt.Errorf("gx: scan string: %w", err)
-		}
-		*i = ID(u)
-		return nil
-	case uint64:
-		*i = ID(v)
-		return nil
-	case nil:
-		*i = 0
-		return nil
-	default:
-		return fmt.Errorf("gx: unsupported scan type %T", src)
+	u, err := scanID(src, decodeDefault)
+	if err != nil {
+		return err
 	}
+	*i = ID(u)
+	return nil
 }
 
-func (i ID) String() string {
-	c := defaultCodec.Load()
-	if c == nil {
-		return fmt.Sprintf("ID(%d)", uint64(i))
+func decodeDefault(s string) (uint64, error) {
+	c, err := getCodec()
+	if err != nil {
+		return 0, err
 	}
-	return c.EncodeUint64(uint64(i))
+	return c.DecodeToUint64(s)
+}
+
+// String returns the encoded ID, or RedactedID when no codec is set.
+func (i ID) String() string {
+	return i.Redacted()
 }
 
 var _ encoding.TextMarshaler = (*ID)(nil)
@@ -125,6 +126,9 @@ func (i ID) MarshalText() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if a, ok := c.(idAppender); ok {
+		return a.AppendEncode(make([]byte, 0, 32), uint64(i)), nil
+	}
 	return []byte(c.EncodeUint64(uint64(i))), nil
 }
 
@@ -163,14 +167,83 @@ func (n *NullID) Scan(src any) error {
 	return n.ID.Scan(src)
 }
 
+var _ json.Marshaler = NullID{}
+var _ json.Unmarshaler = (*NullID)(nil)
+
+// MarshalJSON writes null for an invalid NullID and the encoded ID otherwise.
+func (n NullID) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.ID.MarshalJSON()
+}
+
+// UnmarshalJSON treats null and a blank string as an invalid NullID;
+// anything else must decode as an ID.
+func (n *NullID) UnmarshalJSON(b []byte) error {
+	var s string
+	if t := strings.TrimSpace(string(b)); t == "null" || json.Unmarshal(b, &s) == nil && strings.TrimSpace(s) == "" {
+		*n = NullID{}
+		return nil
+	}
+	if err := n.ID.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+func (n NullID) MarshalText() ([]byte, error) {
+	if !n.Valid {
+		return []byte{}, nil
+	}
+	return n.ID.MarshalText()
+}
+
+func (n *NullID) UnmarshalText(b []byte) error {
+	if len(strings.TrimSpace(string(b))) == 0 {
+		*n = NullID{}
+		return nil
+	}
+	if err := n.ID.UnmarshalText(b); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
 func ParseIDString(s string) (*ID, error) {
 	c, err := getCodec()
 	if err != nil {
 		return nil, err
 	}
+	return parseIDWith(c, s)
+}
+
+// ParseIDStringContext is ParseIDString using the codec attached to ctx by
+// WithIDCodec, falling back to the default codec.
+func ParseIDStringContext(ctx context.Context, s string) (*ID, error) {
+	c, err := IDCodecFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseIDWith(c, s)
+}
+
+func parseIDWith(c IDCodec, s string) (*ID, error) {
 	u, err := c.DecodeToUint64(strings.TrimSpace(s))
 	if err != nil {
 		return nil, err
 	}
 	return NewID(u), nil
 }
+
+// EncodeContext encodes i with the codec attached to ctx by WithIDCodec,
+// falling back to the default codec.
+func (i ID) EncodeContext(ctx context.Context) (string, error) {
+	c, err := IDCodecFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.EncodeUint64(uint64(i)), nil
+}
@@ -0,0 +1,511 @@
+package gx
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// IDFormat selects how ID and NullID appear in binary encodings
+// (BinaryMarshaler, MessagePack, CBOR, BSON, protobuf wrappers). Decoding
+// accepts both forms.
+type IDFormat uint8
+
+const (
+	IDFormatToken IDFormat = iota // encoded token string, same as JSON
+	IDFormatRaw                   // plain unsigned integer
+)
+
+// IDFormatter can be implemented by a codec to choose the binary format of
+// the IDs it encodes.
+type IDFormatter interface {
+	IDFormat() IDFormat
+}
+
+var (
+	defaultIDFormat atomic.Uint32
+	codecFormats    sync.Map // IDCodec -> IDFormat
+)
+
+// SetDefaultIDFormat sets the binary format used when the codec does not pick
+// one.
+func SetDefaultIDFormat(f IDFormat) {
+	defaultIDFormat.Store(uint32(f))
+}
+
+// SetIDCodecFormat sets the binary format for IDs encoded by c, which must be
+// a comparable value such as *idcodec.Codec.
+func SetIDCodecFormat(c IDCodec, f IDFormat) {
+	if c == nil || !reflect.TypeOf(c).Comparable() {
+		panic(fmt.Sprintf("gx: SetIDCodecFormat: %T is not comparable", c))
+	}
+	codecFormats.Store(c, f)
+}
+
+func currentIDFormat() IDFormat {
+	c := defaultCodec.Load()
+	if f, ok := c.(IDFormatter); ok {
+		return f.IDFormat()
+	}
+	if c != nil && reflect.TypeOf(c).Comparable() {
+		if f, ok := codecFormats.Load(c); ok {
+			return f.(IDFormat)
+		}
+	}
+	return IDFormat(defaultIDFormat.Load())
+}
+
+// idWire is an ID as it travels: either a raw number or a token.
+type idWire struct {
+	raw   uint64
+	tok   string
+	isTok bool
+}
+
+func wireOf(u uint64, f IDFormat) (idWire, error) {
+	if f == IDFormatRaw {
+		return idWire{raw: u}, nil
+	}
+	c, err := getCodec()
+	if err != nil {
+		return idWire{}, err
+	}
+	return idWire{tok: c.EncodeUint64(u), isTok: true}, nil
+}
+
+func (w idWire) value() (uint64, error) {
+	if !w.isTok {
+		return w.raw, nil
+	}
+	u, err := decodeDefault(w.tok)
+	if err != nil && !errors.Is(err, errNoDefaultCodec) {
+		return 0, fmt.Errorf("gx: invalid encoded ID: %w", err)
+	}
+	return u, err
+}
+
+var errIDWire = errors.New("gx: malformed binary ID")
+
+// ---------- BinaryMarshaler ----------
+// Raw IDs are 0x00 followed by 8 big-endian bytes; tokens are their chars,
+// which are printable and so never start with 0x00. Empty means null.
+
+func appendBinaryID(dst []byte, w idWire) []byte {
+	if w.isTok {
+		return append(dst, w.tok...)
+	}
+	dst = append(dst, 0)
+	return binary.BigEndian.AppendUint64(dst, w.raw)
+}
+
+func readBinaryID(b []byte) (idWire, bool, error) {
+	switch {
+	case len(b) == 0:
+		return idWire{}, true, nil
+	case b[0] == 0:
+		if len(b) != 9 {
+			return idWire{}, false, errIDWire
+		}
+		return idWire{raw: binary.BigEndian.Uint64(b[1:])}, false, nil
+	default:
+		return idWire{tok: string(b), isTok: true}, false, nil
+	}
+}
+
+// ---------- MessagePack ----------
+
+func appendMsgpackID(dst []byte, w idWire) []byte {
+	if w.isTok {
+		switch n := len(w.tok); {
+		case n < 32:
+			dst = append(dst, 0xa0|byte(n))
+		case n < 256:
+			dst = append(dst, 0xd9, byte(n))
+		default:
+			dst = binary.BigEndian.AppendUint16(append(dst, 0xda), uint16(n))
+		}
+		return append(dst, w.tok...)
+	}
+	switch u := w.raw; {
+	case u < 128:
+		return append(dst, byte(u))
+	case u <= math.MaxUint8:
+		return append(dst, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, 0xce), uint32(u))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, 0xcf), u)
+	}
+}
+
+func readMsgpackID(b []byte) (idWire, bool, error) {
+	if len(b) == 0 {
+		return idWire{}, false, errIDWire
+	}
+	t, p := b[0], b[1:]
+	readUint := func(n int) (idWire, bool, error) {
+		if len(p) != n {
+			return idWire{}, false, errIDWire
+		}
+		var u uint64
+		for _, x := range p {
+			u = u<<8 | uint64(x)
+		}
+		return idWire{raw: u}, false, nil
+	}
+	str := func(lenBytes int) (idWire, bool, error) {
+		if len(p) < lenBytes {
+			return idWire{}, false, errIDWire
+		}
+		var n int
+		for _, x := range p[:lenBytes] {
+			n = n<<8 | int(x)
+		}
+		if len(p) != lenBytes+n {
+			return idWire{}, false, errIDWire
+		}
+		return idWire{tok: string(p[lenBytes:]), isTok: true}, false, nil
+	}
+	switch {
+	case t == 0xc0:
+		return idWire{}, true, nil
+	case t < 0x80:
+		return idWire{raw: uint64(t)}, false, nil
+	case t >= 0xa0 && t <= 0xbf:
+		if len(p) != int(t&0x1f) {
+			return idWire{}, false, errIDWire
+		}
+		return idWire{tok: string(p), isTok: true}, false, nil
+	case t >= 0xcc && t <= 0xcf:
+		return readUint(1 << (t - 0xcc))
+	case t >= 0xd0 && t <= 0xd3: // signed ints from other encoders
+		if len(p) == 0 || p[0]&0x80 != 0 {
+			return idWire{}, false, errIDWire
+		}
+		return readUint(1 << (t - 0xd0))
+	case t == 0xd9:
+		return str(1)
+	case t == 0xda:
+		return str(2)
+	case t == 0xdb:
+		return str(4)
+	}
+	return idWire{}, false, errIDWire
+}
+
+// ---------- CBOR ----------
+
+func appendCBORHead(dst []byte, major byte, u uint64) []byte {
+	m := major << 5
+	switch {
+	case u < 24:
+		return append(dst, m|byte(u))
+	case u <= math.MaxUint8:
+		return append(dst, m|24, byte(u))
+	case u <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(dst, m|25), uint16(u))
+	case u <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(dst, m|26), uint32(u))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, m|27), u)
+	}
+}
+
+func appendCBORID(dst []byte, w idWire) []byte {
+	if w.isTok {
+		return append(appendCBORHead(dst, 3, uint64(len(w.tok))), w.tok...)
+	}
+	return appendCBORHead(dst, 0, w.raw)
+}
+
+func readCBORID(b []byte) (idWire, bool, error) {
+	if len(b) == 0 {
+		return idWire{}, false, errIDWire
+	}
+	if b[0] == 0xf6 || b[0] == 0xf7 { // null, undefined
+		return idWire{}, true, nil
+	}
+	major, info, p := b[0]>>5, b[0]&0x1f, b[1:]
+	var u uint64
+	switch {
+	case info < 24:
+		u = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(p) < n {
+			return idWire{}, false, errIDWire
+		}
+		for _, x := range p[:n] {
+			u = u<<8 | uint64(x)
+		}
+		p = p[n:]
+	default:
+		return idWire{}, false, errIDWire
+	}
+	switch major {
+	case 0:
+		if len(p) != 0 {
+			return idWire{}, false, errIDWire
+		}
+		return idWire{raw: u}, false, nil
+	case 3:
+		if uint64(len(p)) != u {
+			return idWire{}, false, errIDWire
+		}
+		return idWire{tok: string(p), isTok: true}, false, nil
+	}
+	return idWire{}, false, errIDWire
+}
+
+// ---------- BSON (mongo-driver v2 ValueMarshaler) ----------
+
+const (
+	bsonDouble byte = 0x01
+	bsonString byte = 0x02
+	bsonNull   byte = 0x0a
+	bsonInt32  byte = 0x10
+	bsonInt64  byte = 0x12
+)
+
+// bsonID returns the BSON type and payload; raw IDs are int64 since BSON has
+// no unsigned integers.
+func bsonID(w idWire) (byte, []byte, error) {
+	if w.isTok {
+		b := binary.LittleEndian.AppendUint32(nil, uint32(len(w.tok)+1))
+		return bsonString, append(append(b, w.tok...), 0), nil
+	}
+	if w.raw > math.MaxInt64 {
+		return 0, nil, fmt.Errorf("%w: %d", ErrIDOverflow, w.raw)
+	}
+	return bsonInt64, binary.LittleEndian.AppendUint64(nil, w.raw), nil
+}
+
+func readBSONID(t byte, b []byte) (idWire, bool, error) {
+	switch t {
+	case bsonNull:
+		return idWire{}, true, nil
+	case bsonInt64:
+		if len(b) != 8 {
+			break
+		}
+		n := int64(binary.LittleEndian.Uint64(b))
+		if n < 0 {
+			break
+		}
+		return idWire{raw: uint64(n)}, false, nil
+	case bsonInt32:
+		if len(b) != 4 {
+			break
+		}
+		n := int32(binary.LittleEndian.Uint32(b))
+		if n < 0 {
+			break
+		}
+		return idWire{raw: uint64(n)}, false, nil
+	case bsonDouble:
+		if len(b) != 8 {
+			break
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(b))
+		if f < 0 || f >= math.MaxUint64 || f != math.Trunc(f) {
+			break
+		}
+		return idWire{raw: uint64(f)}, false, nil
+	case bsonString:
+		if len(b) < 5 || int(binary.LittleEndian.Uint32(b)) != len(b)-4 || b[len(b)-1] != 0 {
+			break
+		}
+		return idWire{tok: string(b[4 : len(b)-1]), isTok: true}, false, nil
+	}
+	return idWire{}, false, errIDWire
+}
+
+// ---------- protobuf wrappers ----------
+// MarshalProtoWrapper returns a serialized google.protobuf.UInt64Value for raw
+// IDs and a google.protobuf.StringValue for tokens: a bare field 1, varint or
+// bytes. It is not a proto.Message; proto.Unmarshal the bytes into the
+// wrapper type, or put them in a bytes field. UnmarshalProtoWrapper reads
+// either wrapper.
+
+func appendProtoID(dst []byte, w idWire) []byte {
+	if w.isTok {
+		if w.tok == "" {
+			return dst
+		}
+		dst = binary.AppendUvarint(append(dst, 0x0a), uint64(len(w.tok)))
+		return append(dst, w.tok...)
+	}
+	if w.raw == 0 {
+		return dst // proto3 omits default values
+	}
+	return binary.AppendUvarint(append(dst, 0x08), w.raw)
+}
+
+func readProtoID(b []byte) (idWire, error) {
+	var w idWire
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return idWire{}, errIDWire
+		}
+		b = b[n:]
+		switch tag {
+		case 0x08:
+			u, n := binary.Uvarint(b)
+			if n <= 0 {
+				return idWire{}, errIDWire
+			}
+			w, b = idWire{raw: u}, b[n:]
+		case 0x0a:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return idWire{}, errIDWire
+			}
+			w, b = idWire{tok: string(b[n : n+int(l)]), isTok: true}, b[n+int(l):]
+		default:
+			return idWire{}, errIDWire
+		}
+	}
+	return w, nil
+}
+
+// ---------- ID ----------
+
+var _ encoding.BinaryMarshaler = ID(0)
+var _ encoding.BinaryUnmarshaler = (*ID)(nil)
+
+func (i ID) MarshalBinary() ([]byte, error)  { return i.As(currentIDFormat()).MarshalBinary() }
+func (i ID) MarshalMsgpack() ([]byte, error) { return i.As(currentIDFormat()).MarshalMsgpack() }
+func (i ID) MarshalCBOR() ([]byte, error)    { return i.As(currentIDFormat()).MarshalCBOR() }
+func (i ID) MarshalProtoWrapper() ([]byte, error) {
+	return i.As(currentIDFormat()).MarshalProtoWrapper()
+}
+func (i ID) MarshalBSONValue() (byte, []byte, error) {
+	return i.As(currentIDFormat()).MarshalBSONValue()
+}
+
+func (i *ID) setWire(w idWire, null bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if null {
+		*i = 0
+		return nil
+	}
+	u, err := w.value()
+	if err != nil {
+		return err
+	}
+	*i = ID(u)
+	return nil
+}
+
+func (i *ID) UnmarshalBinary(b []byte) error  { return i.setWire(readBinaryID(b)) }
+func (i *ID) UnmarshalMsgpack(b []byte) error { return i.setWire(readMsgpackID(b)) }
+func (i *ID) UnmarshalCBOR(b []byte) error    { return i.setWire(readCBORID(b)) }
+func (i *ID) UnmarshalBSONValue(t byte, b []byte) error {
+	return i.setWire(readBSONID(t, b))
+}
+func (i *ID) UnmarshalProtoWrapper(b []byte) error {
+	w, err := readProtoID(b)
+	return i.setWire(w, false, err)
+}
+
+// FormattedID is an ID pinned to a binary format, for choosing the format per
+// call or per struct field regardless of the codec's setting.
+type FormattedID struct {
+	ID     ID
+	Format IDFormat
+}
+
+// As returns i pinned to format f.
+func (i ID) As(f IDFormat) FormattedID { return FormattedID{ID: i, Format: f} }
+
+func (f FormattedID) marshal(enc func([]byte, idWire) []byte) ([]byte, error) {
+	w, err := wireOf(uint64(f.ID), f.Format)
+	if err != nil {
+		return nil, err
+	}
+	return enc(nil, w), nil
+}
+
+func (f FormattedID) MarshalBinary() ([]byte, error)       { return f.marshal(appendBinaryID) }
+func (f FormattedID) MarshalMsgpack() ([]byte, error)      { return f.marshal(appendMsgpackID) }
+func (f FormattedID) MarshalCBOR() ([]byte, error)         { return f.marshal(appendCBORID) }
+func (f FormattedID) MarshalProtoWrapper() ([]byte, error) { return f.marshal(appendProtoID) }
+func (f FormattedID) MarshalBSONValue() (byte, []byte, error) {
+	w, err := wireOf(uint64(f.ID), f.Format)
+	if err != nil {
+		return 0, nil, err
+	}
+	return bsonID(w)
+}
+
+func (f *FormattedID) UnmarshalBinary(b []byte) error       { return f.ID.UnmarshalBinary(b) }
+func (f *FormattedID) UnmarshalMsgpack(b []byte) error      { return f.ID.UnmarshalMsgpack(b) }
+func (f *FormattedID) UnmarshalCBOR(b []byte) error         { return f.ID.UnmarshalCBOR(b) }
+func (f *FormattedID) UnmarshalProtoWrapper(b []byte) error { return f.ID.UnmarshalProtoWrapper(b) }
+func (f *FormattedID) UnmarshalBSONValue(t byte, b []byte) error {
+	return f.ID.UnmarshalBSONValue(t, b)
+}
+
+// ---------- NullID ----------
+// An invalid NullID is written as the format's null, empty bytes for
+// BinaryMarshaler. Protobuf wrappers have no null, so NullID leaves them out.
+
+func (n NullID) MarshalBinary() ([]byte, error) {
+	if !n.Valid {
+		return []byte{}, nil
+	}
+	return n.ID.MarshalBinary()
+}
+
+func (n NullID) MarshalMsgpack() ([]byte, error) {
+	if !n.Valid {
+		return []byte{0xc0}, nil
+	}
+	return n.ID.MarshalMsgpack()
+}
+
+func (n NullID) MarshalCBOR() ([]byte, error) {
+	if !n.Valid {
+		return []byte{0xf6}, nil
+	}
+	return n.ID.MarshalCBOR()
+}
+
+func (n NullID) MarshalBSONValue() (byte, []byte, error) {
+	if !n.Valid {
+		return bsonNull, nil, nil
+	}
+	return n.ID.MarshalBSONValue()
+}
+
+func (n *NullID) setWire(w idWire, null bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if null {
+		*n = NullID{}
+		return nil
+	}
+	if err := n.ID.setWire(w, false, nil); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+func (n *NullID) UnmarshalBinary(b []byte) error  { return n.setWire(readBinaryID(b)) }
+func (n *NullID) UnmarshalMsgpack(b []byte) error { return n.setWire(readMsgpackID(b)) }
+func (n *NullID) UnmarshalCBOR(b []byte) error    { return n.setWire(readCBORID(b)) }
+func (n *NullID) UnmarshalBSONValue(t byte, b []byte) error {
+	return n.setWire(readBSONID(t, b))
+}
@@ -0,0 +1,154 @@
+package gx
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestIDBinaryRoundTrip(t *testing.T) {
+	SetDefaultCodec(codecForGX(t))
+	for _, f := range []IDFormat{IDFormatToken, IDFormatRaw} {
+		for _, u := range []uint64{0, 1, 127, 128, 300, 70000, 1 << 40, math.MaxInt64} {
+			id := ID(u).As(f)
+			b, _ := id.MarshalBinary()
+			m, _ := id.MarshalMsgpack()
+			c, _ := id.MarshalCBOR()
+			p, _ := id.MarshalProtoWrapper()
+			bt, bv, err := id.MarshalBSONValue()
+			if err != nil {
+				t.Fatalf("bson: %v", err)
+			}
+			var x1, x2, x3, x4, x5 ID
+			if err := x1.UnmarshalBinary(b); err != nil || x1 != ID(u) {
+				t.Fatalf("binary %d/%d: %d %v", f, u, x1, err)
+			}
+			if err := x2.UnmarshalMsgpack(m); err != nil || x2 != ID(u) {
+				t.Fatalf("msgpack %d/%d: %d %v", f, u, x2, err)
+			}
+			if err := x3.UnmarshalCBOR(c); err != nil || x3 != ID(u) {
+				t.Fatalf("cbor %d/%d: %d %v", f, u, x3, err)
+			}
+			if err := x4.UnmarshalProtoWrapper(p); err != nil || x4 != ID(u) {
+				t.Fatalf("proto %d/%d: %d %v", f, u, x4, err)
+			}
+			if err := x5.UnmarshalBSONValue(bt, bv); err != nil || x5 != ID(u) {
+				t.Fatalf("bson %d/%d: %d %v", f, u, x5, err)
+			}
+		}
+	}
+}
+
+func TestIDBinaryWireBytes(t *testing.T) {
+	SetDefaultCodec(codecForGX(t))
+	raw := ID(300).As(IDFormatRaw)
+	tok := ID(300).String()
+	cases := []struct {
+		name string
+		got  func() ([]byte, error)
+		want []byte
+	}{
+		{"msgpack raw", raw.MarshalMsgpack, []byte{0xcd, 0x01, 0x2c}},
+		{"cbor raw", raw.MarshalCBOR, []byte{0x19, 0x01, 0x2c}},
+		{"proto raw", raw.MarshalProtoWrapper, []byte{0x08, 0xac, 0x02}},
+		{"binary raw", raw.MarshalBinary, []byte{0, 0, 0, 0, 0, 0, 0, 0x01, 0x2c}},
+		{"msgpack token", ID(300).MarshalMsgpack, append([]byte{0xa0 | byte(len(tok))}, tok...)},
+		{"cbor token", ID(300).MarshalCBOR, append([]byte{0x60 | byte(len(tok))}, tok...)},
+	}
+	for _, tc := range cases {
+		b, err := tc.got()
+		if err != nil || !bytes.Equal(b, tc.want) {
+			t.Fatalf("%s: got % x want % x (%v)", tc.name, b, tc.want, err)
+		}
+	}
+	if _, _, err := ID(math.MaxUint64).As(IDFormatRaw).MarshalBSONValue(); err == nil {
+		t.Fatalf("bson must reject ids above MaxInt64")
+	}
+	var id ID
+	if err := id.UnmarshalMsgpack([]byte{0xc3}); err == nil {
+		t.Fatalf("want error for msgpack bool")
+	}
+	if err := id.UnmarshalMsgpack([]byte{0xa3, 'x', 'y', 'z'}); err == nil {
+		t.Fatalf("want error for bad token")
+	}
+}
+
+func TestIDProtoWrapper(t *testing.T) {
+	SetDefaultCodec(codecForGX(t))
+	for _, u := range []uint64{0, 1, 300, math.MaxUint64} {
+		b, err := ID(u).As(IDFormatRaw).MarshalProtoWrapper()
+		var raw wrapperspb.UInt64Value
+		if err != nil || proto.Unmarshal(b, &raw) != nil || raw.GetValue() != u {
+			t.Fatalf("raw %d: UInt64Value %v from % x (%v)", u, raw.GetValue(), b, err)
+		}
+		b, err = ID(u).As(IDFormatToken).MarshalProtoWrapper()
+		var tok wrapperspb.StringValue
+		if err != nil || proto.Unmarshal(b, &tok) != nil || tok.GetValue() != ID(u).String() {
+			t.Fatalf("token %d: StringValue %q from % x (%v)", u, tok.GetValue(), b, err)
+		}
+
+		for _, m := range []proto.Message{wrapperspb.UInt64(u), wrapperspb.String(ID(u).String())} {
+			b, err := proto.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var id ID
+			if err := id.UnmarshalProtoWrapper(b); err != nil || id != ID(u) {
+				t.Fatalf("%T %d: got %d %v", m, u, id, err)
+			}
+		}
+	}
+}
+
+func TestIDBinaryFormatSelection(t *testing.T) {
+	c := codecForGX(t)
+	SetDefaultCodec(c)
+	defer SetDefaultIDFormat(IDFormatToken)
+	defer codecFormats.Delete(c)
+
+	if m, _ := ID(5).MarshalMsgpack(); len(m) == 1 {
+		t.Fatalf("default must be token: % x", m)
+	}
+	SetIDCodecFormat(c, IDFormatRaw)
+	if m, _ := ID(5).MarshalMsgpack(); !bytes.Equal(m, []byte{5}) {
+		t.Fatalf("per-codec raw: % x", m)
+	}
+	codecFormats.Delete(c)
+	SetDefaultIDFormat(IDFormatRaw)
+	if m, _ := ID(5).MarshalCBOR(); !bytes.Equal(m, []byte{5}) {
+		t.Fatalf("default raw: % x", m)
+	}
+}
+
+func TestNullIDBinary(t *testing.T) {
+	SetDefaultCodec(codecForGX(t))
+	var n NullID
+	if m, _ := n.MarshalMsgpack(); !bytes.Equal(m, []byte{0xc0}) {
+		t.Fatalf("msgpack null: % x", m)
+	}
+	if c, _ := n.MarshalCBOR(); !bytes.Equal(c, []byte{0xf6}) {
+		t.Fatalf("cbor null: % x", c)
+	}
+	if bt, _, _ := n.MarshalBSONValue(); bt != bsonNull {
+		t.Fatalf("bson null: %x", bt)
+	}
+
+	v := NullID{ID: 9, Valid: true}
+	m, _ := v.MarshalMsgpack()
+	if err := n.UnmarshalMsgpack(m); err != nil || n != v {
+		t.Fatalf("msgpack: %+v %v", n, err)
+	}
+	if err := n.UnmarshalCBOR([]byte{0xf6}); err != nil || n.Valid {
+		t.Fatalf("cbor null: %+v %v", n, err)
+	}
+	b, _ := v.MarshalBinary()
+	if err := n.UnmarshalBinary(b); err != nil || n != v {
+		t.Fatalf("binary: %+v %v", n, err)
+	}
+	if err := n.UnmarshalBinary(nil); err != nil || n.Valid {
+		t.Fatalf("binary null: %+v %v", n, err)
+	}
+}
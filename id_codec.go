@@ -0,0 +1,184 @@
+package gx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bronystylecrazy/gx/idcodec"
+)
+
+// IDCodec is what gx.ID needs from a codec. *idcodec.Codec,
+// *idcodec.Registry and idcodec.MultiCodec all implement it.
+type IDCodec interface {
+	EncodeUint64(id uint64) string
+	DecodeToUint64(s string) (uint64, error)
+	Validate(s string) error
+}
+
+// KindIDCodec is an IDCodec that can bind tokens to a kind; TypedID requires it.
+type KindIDCodec interface {
+	IDCodec
+	EncodeUint64WithKind(id uint64, kind *byte) string
+	DecodeToUint64ExpectKind(s string, kind *byte, others ...*byte) (uint64, error)
+	Kind() *byte
+}
+
+// UUIDCodec is an IDCodec that also obfuscates 128-bit values; OpaqueUUID
+// requires it.
+type UUIDCodec interface {
+	IDCodec
+	EncodeUUID(u [16]byte) string
+	DecodeUUID(s string) ([16]byte, error)
+}
+
+var (
+	_ UUIDCodec   = (*idcodec.Codec)(nil)
+	_ UUIDCodec   = (*idcodec.Registry)(nil)
+	_ KindIDCodec = (*idcodec.Codec)(nil)
+	_ KindIDCodec = (*idcodec.Registry)(nil)
+	_ IDCodec     = idcodec.MultiCodec{}
+)
+
+// codecSlot stores an IDCodec atomically; a nil Store clears it.
+type codecSlot struct {
+	p atomic.Pointer[codecBox]
+}
+
+type codecBox struct{ c IDCodec }
+
+func (s *codecSlot) Store(c IDCodec) {
+	if c == nil {
+		s.p.Store(nil)
+		return
+	}
+	s.p.Store(&codecBox{c: c})
+}
+
+func (s *codecSlot) Load() IDCodec {
+	if b := s.p.Load(); b != nil {
+		return b.c
+	}
+	return nil
+}
+
+var defaultCodec codecSlot
+
+func isNilCodec(c IDCodec) bool {
+	if c == nil {
+		return true
+	}
+	v := reflect.ValueOf(c)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+func SetDefaultCodec(c IDCodec) {
+	if isNilCodec(c) {
+		panic("gx: codec cannot be nil")
+	}
+	defaultCodec.Store(c)
+}
+
+// SetDefaultRegistry makes gx.ID encode with r's current codec and decode
+// tokens of every version registered in r.
+func SetDefaultRegistry(r *idcodec.Registry) {
+	if r == nil {
+		panic("gx: registry cannot be nil")
+	}
+	SetDefaultCodec(r)
+}
+
+var errNoDefaultCodec = errors.New("gx: DefaultCodec is nil (call gx.SetDefaultCodec at boot)")
+
+func getCodec() (IDCodec, error) {
+	c := defaultCodec.Load()
+	if c == nil {
+		return nil, errNoDefaultCodec
+	}
+	return c, nil
+}
+
+// ------------------------------------------------------------
+// Named codecs (per-struct-field selection)
+// ------------------------------------------------------------
+
+// IDCodecScope selects a codec registered with RegisterIDCodec. Implement it
+// on the kind type of a TypedID, or use it with ScopedID, to pick a codec per
+// struct field:
+//
+//	type TenantA struct{}
+//
+//	func (TenantA) IDCodecName() string { return "tenant-a" }
+//
+//	type Row struct {
+//		ID gx.ScopedID[TenantA] `json:"id"`
+//	}
+type IDCodecScope interface {
+	IDCodecName() string
+}
+
+var namedCodecs struct {
+	mu sync.RWMutex
+	m  map[string]IDCodec
+}
+
+// RegisterIDCodec registers c under name, replacing any previous codec.
+func RegisterIDCodec(name string, c IDCodec) {
+	if isNilCodec(c) {
+		panic("gx: codec cannot be nil")
+	}
+	namedCodecs.mu.Lock()
+	defer namedCodecs.mu.Unlock()
+	if namedCodecs.m == nil {
+		namedCodecs.m = make(map[string]IDCodec)
+	}
+	namedCodecs.m[name] = c
+}
+
+// LookupIDCodec returns the codec registered under name.
+func LookupIDCodec(name string) (IDCodec, error) {
+	namedCodecs.mu.RLock()
+	c := namedCodecs.m[name]
+	namedCodecs.mu.RUnlock()
+	if c == nil {
+		return nil, fmt.Errorf("gx: no codec registered as %q", name)
+	}
+	return c, nil
+}
+
+// scopeCodec returns the codec selected by S when it implements IDCodecScope,
+// otherwise the default codec.
+func scopeCodec[S any]() (IDCodec, error) {
+	var s S
+	if sc, ok := any(s).(IDCodecScope); ok {
+		return LookupIDCodec(sc.IDCodecName())
+	}
+	return getCodec()
+}
+
+// ------------------------------------------------------------
+// Context codecs (per-request / per-tenant selection)
+// ------------------------------------------------------------
+
+type idCodecCtxKey struct{}
+
+// WithIDCodec returns a copy of ctx carrying c, for services that host
+// several tenants with different secrets.
+func WithIDCodec(ctx context.Context, c IDCodec) context.Context {
+	if isNilCodec(c) {
+		panic("gx: codec cannot be nil")
+	}
+	return context.WithValue(ctx, idCodecCtxKey{}, c)
+}
+
+// IDCodecFromContext returns the codec attached by WithIDCodec, or the
+// default codec.
+func IDCodecFromContext(ctx context.Context) (IDCodec, error) {
+	if c, ok := ctx.Value(idCodecCtxKey{}).(IDCodec); ok {
+		return c, nil
+	}
+	return getCodec()
+}
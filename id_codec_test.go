@@ -0,0 +1,92 @@
+package gx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bronystylecrazy/gx/idcodec"
+)
+
+func TestSetDefaultCodec_MultiCodec(t *testing.T) {
+	defaultCodec.Store(nil)
+	old := codecForGX(t)
+	cur := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("gx-cur"), Version: 1, MacLen: 6, AllowWeakSecret: true})
+	SetDefaultCodec(idcodec.MultiCodec{Cur: cur, Old: []*idcodec.Codec{old}})
+	p, err := ParseIDString(old.EncodeUint64(12))
+	if err != nil || p.Uint64() != 12 {
+		t.Fatalf("MultiCodec decode via gx.ID failed: %v", err)
+	}
+	if ID(12).String() != cur.EncodeUint64(12) {
+		t.Fatalf("MultiCodec should encode with Cur")
+	}
+	if _, err := ParseTypedIDString[userKind](cur.EncodeUint64(12)); err == nil {
+		t.Fatalf("TypedID should reject codecs without kind support")
+	}
+}
+
+func TestSetDefaultCodec_PanicOnTypedNil(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	var c *idcodec.Codec
+	SetDefaultCodec(c)
+}
+
+func TestIDCodecFromContext(t *testing.T) {
+	defaultCodec.Store(nil)
+	SetDefaultCodec(codecForGX(t))
+	tenant := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("tenant"), MacLen: 6, AllowWeakSecret: true})
+	ctx := WithIDCodec(context.Background(), tenant)
+
+	s, err := ID(5).EncodeContext(ctx)
+	if err != nil || s != tenant.EncodeUint64(5) {
+		t.Fatalf("EncodeContext should use the tenant codec: %v", err)
+	}
+	if p, err := ParseIDStringContext(ctx, s); err != nil || p.Uint64() != 5 {
+		t.Fatalf("ParseIDStringContext failed: %v", err)
+	}
+	if _, err := ParseIDStringContext(context.Background(), s); err == nil {
+		t.Fatalf("default codec should reject tenant token")
+	}
+}
+
+type tenantA struct{}
+
+func (tenantA) IDCodecName() string { return "test-tenant-a" }
+
+type tenantB struct{}
+
+func (tenantB) IDCodecName() string { return "test-tenant-b" }
+
+func TestScopedID_PerFieldCodec(t *testing.T) {
+	a := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("a"), MacLen: 6, AllowWeakSecret: true})
+	b := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("b"), MacLen: 6, AllowWeakSecret: true})
+	RegisterIDCodec("test-tenant-a", a)
+	RegisterIDCodec("test-tenant-b", b)
+	type Row struct {
+		A ScopedID[tenantA] `json:"a"`
+		B ScopedID[tenantB] `json:"b"`
+	}
+	raw, err := json.Marshal(Row{A: 1, B: 1})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err != nil {
+		t.Fatalf("unmarshal raw: %v", err)
+	}
+	if m["a"] != a.EncodeUint64(1) || m["b"] != b.EncodeUint64(1) {
+		t.Fatalf("fields should use their own codecs: %v", m)
+	}
+	var r Row
+	if err := json.Unmarshal(raw, &r); err != nil || r.A != 1 || r.B != 1 {
+		t.Fatalf("round-trip failed: %v", err)
+	}
+	swapped := []byte(`{"a":"` + m["b"] + `"}`)
+	if err := json.Unmarshal(swapped, &r); err == nil {
+		t.Fatalf("token of tenant b should not decode as tenant a")
+	}
+}
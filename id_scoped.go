@@ -0,0 +1,83 @@
+package gx
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+)
+
+// ScopedID is an ID encoded with the codec registered under S's
+// IDCodecName, letting one struct mix fields from different tenants or
+// secrets.
+type ScopedID[S IDCodecScope] uint64
+
+func scopedDecode[S IDCodecScope](s string) (uint64, error) {
+	c, err := scopeCodec[S]()
+	if err != nil {
+		return 0, err
+	}
+	return c.DecodeToUint64(s)
+}
+
+func NewScopedID[S IDCodecScope](u uint64) *ScopedID[S] { x := ScopedID[S](u); return &x }
+func (i ScopedID[S]) Uint64() uint64                    { return uint64(i) }
+func (i ScopedID[S]) ID() ID                            { return ID(i) }
+
+func (i ScopedID[S]) encode() (string, error) {
+	c, err := scopeCodec[S]()
+	if err != nil {
+		return "", err
+	}
+	return c.EncodeUint64(uint64(i)), nil
+}
+
+func (i ScopedID[S]) MarshalJSON() ([]byte, error) {
+	s, err := i.encode()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+func (i *ScopedID[S]) UnmarshalJSON(b []byte) error {
+	u, err := unmarshalIDJSON(b, scopedDecode[S])
+	if err != nil {
+		return err
+	}
+	*i = ScopedID[S](u)
+	return nil
+}
+
+func (i ScopedID[S]) MarshalText() ([]byte, error) {
+	s, err := i.encode()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+func (i *ScopedID[S]) UnmarshalText(b []byte) error {
+	u, err := scopedDecode[S](strings.TrimSpace(string(b)))
+	if err != nil {
+		return err
+	}
+	*i = ScopedID[S](u)
+	return nil
+}
+
+func (i ScopedID[S]) Value() (driver.Value, error) {
+	return idValue(uint64(i), i.encode)
+}
+
+func (i *ScopedID[S]) Scan(src any) error {
+	u, err := scanID(src, scopedDecode[S])
+	if err != nil {
+		return err
+	}
+	*i = ScopedID[S](u)
+	return nil
+}
+
+func (i ScopedID[S]) String() string {
+	return i.Redacted()
+}
@@ -0,0 +1,110 @@
+package gx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+)
+
+// IDStorage selects how ID, NullID and TypedID are written to SQL columns.
+// Scan accepts every numeric type regardless of the mode; strings are read as
+// decimals, or as tokens in IDStorageToken.
+type IDStorage int32
+
+const (
+	IDStorageInt64   IDStorage = iota // BIGINT; ids above math.MaxInt64 fail with ErrIDOverflow
+	IDStorageDecimal                  // unsigned decimal string, for NUMERIC(20)/TEXT columns
+	IDStorageToken                    // encoded token string, using the default codec
+)
+
+var ErrIDOverflow = errors.New("gx: ID overflows int64 (use IDStorageDecimal)")
+
+var idStorage atomic.Int32
+
+// SetIDStorage sets the storage mode used by Value and Scan; call it at boot.
+func SetIDStorage(m IDStorage) {
+	idStorage.Store(int32(m))
+}
+
+func CurrentIDStorage() IDStorage {
+	return IDStorage(idStorage.Load())
+}
+
+// idValue implements driver.Valuer for id types; encode is used in token mode.
+func idValue(u uint64, encode func() (string, error)) (driver.Value, error) {
+	switch CurrentIDStorage() {
+	case IDStorageDecimal:
+		return strconv.FormatUint(u, 10), nil
+	case IDStorageToken:
+		return encode()
+	default:
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %d", ErrIDOverflow, u)
+		}
+		return int64(u), nil
+	}
+}
+
+// scanID implements sql.Scanner for id types; decode parses strings in token
+// mode. Negative values of any signed type are read back as their 64-bit
+// two's complement, so rows written before overflow checking keep their ids
+// whichever integer type the driver scans them into.
+func scanID(src any, decode func(string) (uint64, error)) (uint64, error) {
+	parse := func(s string) (uint64, error) {
+		if CurrentIDStorage() == IDStorageToken {
+			return decode(s)
+		}
+		return strconv.ParseUint(s, 10, 64)
+	}
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case []byte:
+		u, err := parse(string(v))
+		if err != nil {
+			return 0, fmt.Errorf("gx: scan []byte: %w", err)
+		}
+		return u, nil
+	case string:
+		u, err := parse(v)
+		if err != nil {
+			return 0, fmt.Errorf("gx: scan string: %w", err)
+		}
+		return u, nil
+	case driver.Valuer:
+		// driver-specific wrappers such as pgtype.Int8
+		dv, err := v.Value()
+		if err != nil {
+			return 0, fmt.Errorf("gx: scan %T: %w", src, err)
+		}
+		if _, again := dv.(driver.Valuer); again {
+			return 0, fmt.Errorf("gx: unsupported scan type %T", src)
+		}
+		return scanID(dv, decode)
+	}
+
+	rv := reflect.ValueOf(src)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f < 0 || f >= math.MaxUint64 || f != math.Trunc(f) {
+			return 0, fmt.Errorf("gx: scan %T: %v is not a valid id", src, f)
+		}
+		return uint64(f), nil
+	case reflect.String:
+		return scanID(rv.String(), decode)
+	}
+	return 0, fmt.Errorf("gx: unsupported scan type %T", src)
+}
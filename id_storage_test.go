@@ -0,0 +1,127 @@
+package gx
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+type pgInt8 struct{ v int64 }
+
+func (p pgInt8) Value() (driver.Value, error) { return p.v, nil }
+
+type myInt int32
+
+func TestIDStorageModes(t *testing.T) {
+	SetDefaultCodec(codecForGX(t))
+	defer SetIDStorage(IDStorageInt64)
+
+	big := ID(math.MaxUint64 - 1)
+	if _, err := big.Value(); !errors.Is(err, ErrIDOverflow) {
+		t.Fatalf("want ErrIDOverflow, got %v", err)
+	}
+	if v, err := ID(7).Value(); err != nil || v != int64(7) {
+		t.Fatalf("int64 mode: %v %v", v, err)
+	}
+
+	SetIDStorage(IDStorageDecimal)
+	v, err := big.Value()
+	if err != nil || v != "18446744073709551614" {
+		t.Fatalf("decimal mode: %v %v", v, err)
+	}
+	var back ID
+	if err := back.Scan(v); err != nil || back != big {
+		t.Fatalf("decimal scan: %v %v", back, err)
+	}
+
+	SetIDStorage(IDStorageToken)
+	v, err = big.Value()
+	if err != nil || v != big.String() {
+		t.Fatalf("token mode: %v %v", v, err)
+	}
+	back = 0
+	if err := back.Scan([]byte(v.(string))); err != nil || back != big {
+		t.Fatalf("token scan: %v %v", back, err)
+	}
+	if err := back.Scan("12345"); err == nil {
+		t.Fatalf("token mode must reject decimals")
+	}
+
+	var typed TypedID[userKind]
+	v, err = TypedID[userKind](9).Value()
+	if err != nil {
+		t.Fatalf("typed Value: %v", err)
+	}
+	if err := typed.Scan(v); err != nil || typed != 9 {
+		t.Fatalf("typed scan: %v %v", typed, err)
+	}
+	var other TypedID[orderKind]
+	if err := other.Scan(v); err == nil {
+		t.Fatalf("typed scan must check kind")
+	}
+}
+
+func TestIDScanTypes(t *testing.T) {
+	for _, src := range []any{int32(5), uint32(5), int(5), uint(5), int16(5), uint8(5), float64(5), float32(5), myInt(5), pgInt8{5}} {
+		var id ID
+		if err := id.Scan(src); err != nil || id != 5 {
+			t.Fatalf("%T: got %d, %v", src, id, err)
+		}
+	}
+	for _, src := range []any{float64(1.5), float64(-3), math.Inf(1), struct{}{}} {
+		var id ID
+		if err := id.Scan(src); err == nil {
+			t.Fatalf("%T(%v): want error", src, src)
+		}
+	}
+	// Every signed type follows the int64 rule for legacy negative ids.
+	for _, src := range []any{int64(-2), int(-2), int32(-2), int8(-2), myInt(-2)} {
+		var id ID
+		if err := id.Scan(src); err != nil || id != ID(math.MaxUint64-1) {
+			t.Fatalf("legacy negative %T: %d %v", src, id, err)
+		}
+	}
+}
+
+func TestNullIDJSONText(t *testing.T) {
+	SetDefaultCodec(codecForGX(t))
+	type row struct {
+		A NullID `json:"a"`
+		B NullID `json:"b"`
+	}
+	b, err := json.Marshal(row{A: NullID{ID: 42, Valid: true}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"a":"` + ID(42).String() + `","b":null}`
+	if string(b) != want {
+		t.Fatalf("got %s want %s", b, want)
+	}
+	var r row
+	if err := json.Unmarshal(b, &r); err != nil || !r.A.Valid || r.A.ID != 42 || r.B.Valid {
+		t.Fatalf("unmarshal: %+v %v", r, err)
+	}
+	if err := json.Unmarshal([]byte(`{"a":"","b":"bogus"}`), &r); err == nil {
+		t.Fatalf("want error for bogus token")
+	}
+	if r.A.Valid {
+		t.Fatalf(`"" must be null`)
+	}
+	r.A = NullID{ID: 1, Valid: true}
+	if err := json.Unmarshal([]byte(`{"a":"  "}`), &r); err != nil || r.A.Valid || r.A.ID != 0 {
+		t.Fatalf("blank string must be null: %+v %v", r.A, err)
+	}
+
+	var n NullID
+	if txt, _ := n.MarshalText(); len(txt) != 0 {
+		t.Fatalf("null text: %q", txt)
+	}
+	if err := n.UnmarshalText([]byte(ID(3).String())); err != nil || !n.Valid || n.ID != 3 {
+		t.Fatalf("text roundtrip: %+v %v", n, err)
+	}
+	if err := n.UnmarshalText(nil); err != nil || n.Valid {
+		t.Fatalf("empty text: %+v %v", n, err)
+	}
+}
@@ -227,12 +227,12 @@ func TestNewID_Uint64_AndEqualityAfterRoundTrip(t *testing.T) {
 func TestString_WithAndWithoutCodec(t *testing.T) {
 	defaultCodec.Store(nil)
 	var id ID = 10
-	if id.String() != "ID(10)" {
+	if id.String() != RedactedID {
 		t.Fatalf("string without codec mismatch")
 	}
 	SetDefaultCodec(codecForGX(t))
 	s := id.String()
-	if s == "ID(10)" || s == "" {
+	if s == RedactedID || s == "" {
 		t.Fatalf("string with codec not encoded")
 	}
 	if _, err := defaultCodec.Load().DecodeToUint64(s); err != nil {
@@ -307,3 +307,20 @@ func TestSetDefaultCodec_PanicOnNil(t *testing.T) {
 	}()
 	SetDefaultCodec(nil)
 }
+
+func TestSetDefaultRegistry_DecodesOldVersions(t *testing.T) {
+	defaultCodec.Store(nil)
+	old := codecForGX(t)
+	cur, err := idcodec.NewCodecFromSecret(idcodec.Config{Secret: []byte("gx-secret-2"), Version: 1, MacLen: 6, AllowWeakSecret: true})
+	if err != nil {
+		t.Fatalf("NewCodecFromSecret: %v", err)
+	}
+	SetDefaultRegistry(idcodec.MustNewRegistry(cur, old))
+	var id ID
+	if err := id.UnmarshalText([]byte(old.EncodeUint64(808))); err != nil || id != 808 {
+		t.Fatalf("old token should decode through registry: %v", err)
+	}
+	if id.String() != cur.EncodeUint64(808) {
+		t.Fatalf("registry should encode with current codec")
+	}
+}
@@ -0,0 +1,235 @@
+package gx
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bronystylecrazy/gx/idcodec"
+)
+
+// IDKind binds a TypedID to an idcodec kind byte. Implement it on an empty
+// struct and register it at boot:
+//
+//	type UserKind struct{}
+//
+//	func (UserKind) Kind() byte { return 'U' }
+//
+//	type UserID = gx.TypedID[UserKind]
+//
+//	func init() { gx.RegisterIDKind[UserKind]() }
+type IDKind interface {
+	Kind() byte
+}
+
+// ErrIDKindMismatch is returned when a token is authentic but was minted for
+// a different kind than the TypedID decoding it.
+var ErrIDKindMismatch = fmt.Errorf("gx: %w", idcodec.ErrKindMismatch)
+
+// ErrIDKindNotRegistered is returned when encoding or decoding a TypedID
+// whose kind was not registered with RegisterIDKind.
+var ErrIDKindNotRegistered = errors.New("gx: id kind not registered")
+
+// TypedID is an ID whose encoded form is bound to the kind of K, so a token
+// issued for one kind does not decode as another.
+type TypedID[K IDKind] uint64
+
+var knownKinds struct {
+	mu    sync.RWMutex
+	kinds []*byte
+}
+
+func kindOf[K IDKind]() *byte {
+	var k K
+	b := k.Kind()
+	return &b
+}
+
+// RegisterIDKind makes K usable as a TypedID kind; encoding or decoding an
+// unregistered kind fails with ErrIDKindNotRegistered. Since every kind in
+// use is known, decoding a token minted for another kind reports
+// ErrIDKindMismatch rather than a MAC error. Call it at boot; each registered
+// kind costs one extra MAC on every token that fails to verify.
+func RegisterIDKind[K IDKind]() {
+	p := kindOf[K]()
+	knownKinds.mu.Lock()
+	defer knownKinds.mu.Unlock()
+	for _, k := range knownKinds.kinds {
+		if *k == *p {
+			return
+		}
+	}
+	knownKinds.kinds = append(knownKinds.kinds, p)
+}
+
+func checkKind[K IDKind]() error {
+	p := kindOf[K]()
+	knownKinds.mu.RLock()
+	defer knownKinds.mu.RUnlock()
+	for _, k := range knownKinds.kinds {
+		if *k == *p {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrIDKindNotRegistered, *p)
+}
+
+func otherKinds(c KindIDCodec) []*byte {
+	knownKinds.mu.RLock()
+	defer knownKinds.mu.RUnlock()
+	out := make([]*byte, 0, len(knownKinds.kinds)+1)
+	out = append(out, c.Kind()) // tokens of a plain gx.ID
+	return append(out, knownKinds.kinds...)
+}
+
+// kindCodec returns the codec for K: the one named by K if it implements
+// IDCodecScope, otherwise the default codec. K must be registered.
+func kindCodec[K IDKind]() (KindIDCodec, error) {
+	if err := checkKind[K](); err != nil {
+		return nil, err
+	}
+	c, err := scopeCodec[K]()
+	if err != nil {
+		return nil, err
+	}
+	return asKindCodec(c)
+}
+
+// kindCodecContext is kindCodec falling back to the codec attached to ctx.
+func kindCodecContext[K IDKind](ctx context.Context) (KindIDCodec, error) {
+	var k K
+	if _, ok := any(k).(IDCodecScope); ok {
+		return kindCodec[K]()
+	}
+	if err := checkKind[K](); err != nil {
+		return nil, err
+	}
+	c, err := IDCodecFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return asKindCodec(c)
+}
+
+func asKindCodec(c IDCodec) (KindIDCodec, error) {
+	kc, ok := c.(KindIDCodec)
+	if !ok {
+		return nil, fmt.Errorf("gx: codec %T does not support kinds", c)
+	}
+	return kc, nil
+}
+
+func decodeTyped[K IDKind](s string) (uint64, error) {
+	c, err := kindCodec[K]()
+	if err != nil {
+		return 0, err
+	}
+	return decodeTypedWith[K](c, s)
+}
+
+func decodeTypedWith[K IDKind](c KindIDCodec, s string) (uint64, error) {
+	u, err := c.DecodeToUint64ExpectKind(s, kindOf[K](), otherKinds(c)...)
+	if errors.Is(err, idcodec.ErrKindMismatch) {
+		return 0, ErrIDKindMismatch
+	}
+	return u, err
+}
+
+func NewTypedID[K IDKind](u uint64) *TypedID[K] { x := TypedID[K](u); return &x }
+func (i TypedID[K]) Uint64() uint64             { return uint64(i) }
+func (i TypedID[K]) ID() ID                     { return ID(i) }
+
+func (i TypedID[K]) encode() (string, error) {
+	c, err := kindCodec[K]()
+	if err != nil {
+		return "", err
+	}
+	return c.EncodeUint64WithKind(uint64(i), kindOf[K]()), nil
+}
+
+func (i TypedID[K]) MarshalJSON() ([]byte, error) {
+	s, err := i.encode()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON accepts null, "" or a token of kind K; raw numbers are
+// rejected since they carry no kind.
+func (i *TypedID[K]) UnmarshalJSON(b []byte) error {
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] != '"' && string(t) != "null" {
+		return errors.New("gx: typed id must be an encoded string")
+	}
+	u, err := unmarshalIDJSON(b, decodeTyped[K])
+	if err != nil {
+		return err
+	}
+	*i = TypedID[K](u)
+	return nil
+}
+
+var _ encoding.TextMarshaler = (*TypedID[IDKind])(nil)
+var _ encoding.TextUnmarshaler = (*TypedID[IDKind])(nil)
+
+func (i TypedID[K]) MarshalText() ([]byte, error) {
+	s, err := i.encode()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+func (i *TypedID[K]) UnmarshalText(b []byte) error {
+	u, err := decodeTyped[K](strings.TrimSpace(string(b)))
+	if err != nil {
+		return err
+	}
+	*i = TypedID[K](u)
+	return nil
+}
+
+func (i TypedID[K]) Value() (driver.Value, error) {
+	return idValue(uint64(i), i.encode)
+}
+
+func (i *TypedID[K]) Scan(src any) error {
+	u, err := scanID(src, decodeTyped[K])
+	if err != nil {
+		return err
+	}
+	*i = TypedID[K](u)
+	return nil
+}
+
+func (i TypedID[K]) String() string {
+	return i.Redacted()
+}
+
+func ParseTypedIDString[K IDKind](s string) (*TypedID[K], error) {
+	u, err := decodeTyped[K](strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return NewTypedID[K](u), nil
+}
+
+// ParseTypedIDStringContext is ParseTypedIDString with the codec attached to
+// ctx by WithIDCodec, unless K names its own codec.
+func ParseTypedIDStringContext[K IDKind](ctx context.Context, s string) (*TypedID[K], error) {
+	c, err := kindCodecContext[K](ctx)
+	if err != nil {
+		return nil, err
+	}
+	u, err := decodeTypedWith[K](c, strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return NewTypedID[K](u), nil
+}
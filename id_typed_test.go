@@ -0,0 +1,106 @@
+package gx
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type userKind struct{}
+
+func (userKind) Kind() byte { return 'U' }
+
+type orderKind struct{}
+
+func (orderKind) Kind() byte { return 'O' }
+
+func init() {
+	RegisterIDKind[userKind]()
+	RegisterIDKind[orderKind]()
+}
+
+func TestTypedID_RoundTrip(t *testing.T) {
+	defaultCodec.Store(nil)
+	SetDefaultCodec(codecForGX(t))
+	type Order struct {
+		ID   TypedID[orderKind] `json:"id"`
+		User *TypedID[userKind] `json:"user"`
+	}
+	o := Order{ID: 42, User: NewTypedID[userKind](7)}
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var o2 Order
+	if err := json.Unmarshal(b, &o2); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if o2.ID != 42 || o2.User == nil || o2.User.Uint64() != 7 {
+		t.Fatalf("round-trip mismatch: %+v", o2)
+	}
+	if ID(o.ID).String() == o.ID.String() {
+		t.Fatalf("typed token should differ from untyped token")
+	}
+}
+
+type invoiceKind struct{}
+
+func (invoiceKind) Kind() byte { return 'I' }
+
+func TestTypedID_WrongKindFails(t *testing.T) {
+	defaultCodec.Store(nil)
+	SetDefaultCodec(codecForGX(t))
+	userTok := TypedID[userKind](99).String()
+	if _, err := ParseTypedIDString[orderKind](userTok); !errors.Is(err, ErrIDKindMismatch) {
+		t.Fatalf("expected kind mismatch, got %v", err)
+	}
+	var oid TypedID[orderKind]
+	if err := json.Unmarshal([]byte(`"`+userTok+`"`), &oid); !errors.Is(err, ErrIDKindMismatch) {
+		t.Fatalf("expected kind mismatch from JSON, got %v", err)
+	}
+	plain := ID(99).String()
+	if _, err := ParseTypedIDString[userKind](plain); !errors.Is(err, ErrIDKindMismatch) {
+		t.Fatalf("untyped token should not decode as typed: %v", err)
+	}
+	if _, err := ParseIDString(userTok); err == nil {
+		t.Fatalf("typed token should not decode as plain ID")
+	}
+	if _, err := ParseTypedIDString[userKind]("bad"); err == nil || errors.Is(err, ErrIDKindMismatch) {
+		t.Fatalf("malformed token should fail without kind mismatch: %v", err)
+	}
+	// Unregistered kinds can neither mint nor read tokens.
+	if _, err := TypedID[invoiceKind](99).MarshalText(); !errors.Is(err, ErrIDKindNotRegistered) {
+		t.Fatalf("unregistered kind should not encode: %v", err)
+	}
+	if _, err := ParseTypedIDString[invoiceKind](userTok); !errors.Is(err, ErrIDKindNotRegistered) {
+		t.Fatalf("unregistered kind should not decode: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`99`), &oid); err == nil {
+		t.Fatalf("raw number must not decode as typed id")
+	}
+	if err := json.Unmarshal([]byte(`null`), &oid); err != nil || oid != 0 {
+		t.Fatalf("null: %v", err)
+	}
+}
+
+func TestTypedID_TextAndSQL(t *testing.T) {
+	defaultCodec.Store(nil)
+	SetDefaultCodec(codecForGX(t))
+	i := TypedID[userKind](31337)
+	b, err := i.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: %v", err)
+	}
+	var j TypedID[userKind]
+	if err := j.UnmarshalText(b); err != nil || j != i {
+		t.Fatalf("text round-trip mismatch: %v", err)
+	}
+	v, err := i.Value()
+	if err != nil || v != int64(31337) {
+		t.Fatalf("Value mismatch: %v %v", v, err)
+	}
+	var k TypedID[userKind]
+	if err := k.Scan(int64(5)); err != nil || k != 5 {
+		t.Fatalf("Scan mismatch: %v", err)
+	}
+}
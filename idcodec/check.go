@@ -0,0 +1,116 @@
+package idcodec
+
+import "errors"
+
+// Check chars: with Config.CheckChar every token ends in a Luhn mod N check
+// digit over the preceding chars. It catches every single-char substitution
+// and most adjacent transpositions before the MAC is consulted, so a typo
+// reports ErrChecksum while a forgery still reports ErrMACVerification.
+
+var ErrChecksum = errors.New("idcodec: check char mismatch (likely a typo)")
+
+// luhnSum folds the digit values of s, doubling every second digit from the
+// right when double starts true.
+func luhnSum[T string | []byte](c *Codec, s T, double bool) (uint64, bool) {
+	n := c.radix
+	var sum uint64
+	for i := len(s) - 1; i >= 0; i-- {
+		v := c.rev[s[i]]
+		if v < 0 {
+			return 0, false
+		}
+		a := uint64(v)
+		if double {
+			a *= 2
+			a = a/n + a%n
+		}
+		sum += a
+		double = !double
+	}
+	return sum % n, true
+}
+
+func (c *Codec) appendCheck(dst []byte, start int) []byte {
+	sum, _ := luhnSum(c, dst[start:], true)
+	return append(dst, c.alphabet[(c.radix-sum)%c.radix])
+}
+
+// verifyCheck validates the trailing check char of s and returns the
+// length of the payload before it.
+func verifyCheck[T string | []byte](c *Codec, s T) (int, error) {
+	if len(s) < 2 {
+		return 0, ErrInvalidLength
+	}
+	sum, ok := luhnSum(c, s, false)
+	if !ok {
+		return 0, ErrInvalidBase62Char
+	}
+	if sum != 0 {
+		return 0, ErrChecksum
+	}
+	return len(s) - 1, nil
+}
+
+// withCheck appends the check char to an allocated token when enabled.
+func (c *Codec) withCheck(s string) string {
+	if !c.check {
+		return s
+	}
+	b := append(make([]byte, 0, len(s)+1), s...)
+	return string(c.appendCheck(b, 0))
+}
+
+// stripCheck validates and removes the check char when enabled.
+func (c *Codec) stripCheck(s string) (string, error) {
+	if !c.check {
+		return s, nil
+	}
+	n, err := verifyCheck(c, s)
+	if err != nil {
+		return "", err
+	}
+	return s[:n], nil
+}
+
+// Suggest proposes valid tokens that differ from s by one substituted char
+// or one swap of adjacent chars, for reporting "did you mean" to users who
+// typed a token by hand. Candidates must pass the MAC, so the result only
+// contains tokens this codec minted. It returns nil when s is already valid.
+func (c *Codec) Suggest(s string) []string {
+	if c.Validate(s) == nil {
+		return nil
+	}
+	var out []string
+	seen := map[string]bool{}
+	try := func(b []byte) {
+		cand := string(b)
+		if seen[cand] {
+			return
+		}
+		seen[cand] = true
+		if c.Validate(cand) == nil {
+			out = append(out, cand)
+		}
+	}
+	b := []byte(s)
+	for i := range b {
+		orig := b[i]
+		for d := 0; d < int(c.radix); d++ {
+			if c.alphabet[d] == orig || c.rev[orig] == int8(d) {
+				continue
+			}
+			b[i] = c.alphabet[d]
+			try(b)
+		}
+		b[i] = orig
+	}
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] == b[i+1] {
+			continue
+		}
+		b[i], b[i+1] = b[i+1], b[i]
+		try(b)
+		b[i], b[i+1] = b[i+1], b[i]
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package idcodec
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func checkCodec(t *testing.T) *Codec {
+	t.Helper()
+	return MustNewCodecFromSecret(Config{Secret: []byte("check"), Version: 1, MacLen: 6, CheckChar: true, AllowWeakSecret: true})
+}
+
+func TestCheckChar_TypoVersusForgery(t *testing.T) {
+	c := checkCodec(t)
+	s := c.EncodeUint64(123456)
+	if len(s) != 1+11+6+1 {
+		t.Fatalf("token length %d", len(s))
+	}
+	if u, err := c.DecodeToUint64(s); err != nil || u != 123456 {
+		t.Fatalf("round-trip: %v", err)
+	}
+	for i := 0; i < len(s); i++ {
+		b := []byte(s)
+		b[i] = c.alphabet[(int(c.rev[b[i]])+7)%62]
+		if _, err := c.DecodeToUint64(string(b)); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("substitution at %d: expected ErrChecksum, got %v", i, err)
+		}
+	}
+
+	// a forger who recomputes the check char still fails the MAC
+	b := []byte(s[:len(s)-1])
+	b[3] = c.alphabet[(int(c.rev[b[3]])+1)%62]
+	forged := string(c.appendCheck(b, 0))
+	if _, err := c.DecodeToUint64(forged); !errors.Is(err, ErrMACVerification) {
+		t.Fatalf("expected MAC failure for forged token, got %v", err)
+	}
+
+	if u, err := c.DecodeUUID(c.EncodeUUID([16]byte{9})); err != nil || u != [16]byte{9} {
+		t.Fatalf("uuid with check char: %v", err)
+	}
+	tok, err := c.EncodeWithClaims(5, Claims{})
+	if err != nil {
+		t.Fatalf("claims with check char: %v", err)
+	}
+	if u, _, err := c.DecodeWithClaims(tok, nil); err != nil || u != 5 {
+		t.Fatalf("claims with check char: %v", err)
+	}
+}
+
+func TestSuggest_SubstitutionAndTransposition(t *testing.T) {
+	for _, check := range []bool{true, false} {
+		c := MustNewCodecFromSecret(Config{Secret: []byte("suggest"), MacLen: 6, CheckChar: check, AllowWeakSecret: true})
+		s := c.EncodeUint64(777)
+
+		b := []byte(s)
+		b[5] = c.alphabet[(int(c.rev[b[5]])+3)%62]
+		if got := c.Suggest(string(b)); !slices.Contains(got, s) {
+			t.Fatalf("check=%v: substitution: want %q in %v", check, s, got)
+		}
+
+		i := 2
+		for s[i] == s[i+1] {
+			i++
+		}
+		b = []byte(s)
+		b[i], b[i+1] = b[i+1], b[i]
+		if got := c.Suggest(string(b)); !slices.Contains(got, s) {
+			t.Fatalf("check=%v: transposition: want %q in %v", check, s, got)
+		}
+		if got := c.Suggest(s); got != nil {
+			t.Fatalf("valid token should have no suggestions: %v", got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package idcodec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Claims is the metadata carried by extended tokens. ExpiresAt is embedded
+// in the token; Audience is not, but is covered by the MAC, so a token only
+// decodes for the audience it was issued to.
+type Claims struct {
+	ExpiresAt time.Time // zero means no expiry
+	Audience  []byte
+}
+
+var (
+	ErrExpired          = errors.New("idcodec: token expired")
+	ErrExpiryOutOfRange = errors.New("idcodec: expiry does not fit in the token")
+)
+
+// Extended tokens: version char + full-width body + 7-digit expiry (unix
+// seconds, 0 = none) + MAC. The body is never minimal here.
+const expLen = 7 // even 32^7 seconds reaches past year 3000
+
+const claimsMarker = 'E'
+
+func (c *Codec) claimsMAC(enc, exp uint64, audience []byte, kind *byte) string {
+	b := make([]byte, 0, 1+8+8+4+len(audience))
+	b = append(b, claimsMarker)
+	b = binary.BigEndian.AppendUint64(b, enc)
+	b = binary.BigEndian.AppendUint64(b, exp)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(audience)))
+	b = append(b, audience...)
+	return c.macChars(b, kind)
+}
+
+// expSeconds maps t to the embedded expiry. Since 0 means none, expiries at
+// or before the unix epoch become 1, which is just as expired.
+func (c *Codec) expSeconds(t time.Time) (uint64, error) {
+	if t.IsZero() {
+		return 0, nil
+	}
+	if t.Unix() <= 0 {
+		return 1, nil
+	}
+	limit := uint64(1)
+	for range expLen {
+		limit *= c.radix
+	}
+	if uint64(t.Unix()) >= limit {
+		return 0, fmt.Errorf("%w: %v is past %v", ErrExpiryOutOfRange, t, time.Unix(int64(limit-1), 0).UTC())
+	}
+	return uint64(t.Unix()), nil
+}
+
+// EncodeWithClaims encodes id with cl; it fails only for expiries too late
+// for the token's expLen digits.
+func (c *Codec) EncodeWithClaims(id uint64, cl Claims) (string, error) {
+	exp, err := c.expSeconds(cl.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	enc := c.permutation(id)
+	b := make([]byte, 0, 1+c.bodyLen+expLen+c.macLen)
+	b = append(b, c.versionChar())
+	b = c.appendDigits(b, enc, c.bodyLen)
+	b = c.appendDigits(b, exp, expLen)
+	return c.withCheck(string(b) + c.claimsMAC(enc, exp, cl.Audience, c.kind)), nil
+}
+
+// DecodeWithClaims verifies s for audience and returns the id with the
+// embedded claims. Expired tokens return ErrExpired along with their claims.
+func (c *Codec) DecodeWithClaims(s string, audience []byte) (uint64, Claims, error) {
+	return c.DecodeWithClaimsAt(s, audience, time.Now())
+}
+
+// DecodeWithClaimsAt is DecodeWithClaims checking expiry against now.
+func (c *Codec) DecodeWithClaimsAt(s string, audience []byte, now time.Time) (uint64, Claims, error) {
+	if c.check && len(s) != 1+c.bodyLen+expLen+c.macLen+1 {
+		return 0, Claims{}, ErrInvalidLength
+	}
+	s, err := c.stripCheck(s)
+	if err != nil {
+		return 0, Claims{}, err
+	}
+	if len(s) != 1+c.bodyLen+expLen+c.macLen {
+		return 0, Claims{}, ErrInvalidLength
+	}
+	if c.rev[s[0]] != int8(c.version) {
+		return 0, Claims{}, ErrVersionMismatch
+	}
+	enc, err := decodeDigits(c, s, 1, c.bodyLen)
+	if err != nil {
+		return 0, Claims{}, err
+	}
+	exp, err := decodeDigits(c, s, 1+c.bodyLen, expLen)
+	if err != nil {
+		return 0, Claims{}, err
+	}
+	want := c.claimsMAC(enc, exp, audience, c.kind)
+	if !macEqual(c, s, 1+c.bodyLen+expLen, want) {
+		return 0, Claims{}, ErrMACVerification
+	}
+	cl := Claims{Audience: append([]byte(nil), audience...)}
+	if exp != 0 {
+		cl.ExpiresAt = time.Unix(int64(exp), 0)
+		if !now.Before(cl.ExpiresAt) {
+			return 0, cl, ErrExpired
+		}
+	}
+	return c.inversePermutation(enc), cl, nil
+}
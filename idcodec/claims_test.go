@@ -0,0 +1,94 @@
+package idcodec
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClaims_ExpiryAndAudience(t *testing.T) {
+	c := newCodecForTest(t, 2, 6, []byte("share"), nil)
+	now := time.Unix(1_700_000_000, 0)
+	aud := []byte("download")
+	s, err := c.EncodeWithClaims(4242, Claims{ExpiresAt: now.Add(time.Hour), Audience: aud})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if len(s) != 1+11+7+6 {
+		t.Fatalf("token length %d", len(s))
+	}
+
+	u, cl, err := c.DecodeWithClaimsAt(s, aud, now)
+	if err != nil || u != 4242 {
+		t.Fatalf("decode: u=%d err=%v", u, err)
+	}
+	if !cl.ExpiresAt.Equal(now.Add(time.Hour)) || string(cl.Audience) != "download" {
+		t.Fatalf("claims mismatch: %+v", cl)
+	}
+
+	_, cl, err = c.DecodeWithClaimsAt(s, aud, now.Add(2*time.Hour))
+	if !errors.Is(err, ErrExpired) || cl.ExpiresAt.IsZero() {
+		t.Fatalf("expected ErrExpired with claims, got %v %+v", err, cl)
+	}
+	if _, _, err := c.DecodeWithClaimsAt(s, []byte("upload"), now); !errors.Is(err, ErrMACVerification) {
+		t.Fatalf("audience must be bound: %v", err)
+	}
+	if _, _, err := c.DecodeWithClaimsAt(tamperLast(c, s), aud, now); !errors.Is(err, ErrMACVerification) {
+		t.Fatalf("expected MAC failure on tamper: %v", err)
+	}
+
+	// bumping the expiry char must not extend the link
+	b := []byte(s)
+	b[1+11+6] = c.alphabet[(int(c.rev[b[1+11+6]])+1)%62]
+	if _, _, err := c.DecodeWithClaimsAt(string(b), aud, now); !errors.Is(err, ErrMACVerification) {
+		t.Fatalf("expiry must be covered by MAC: %v", err)
+	}
+	if _, err := c.DecodeToUint64(s); !errors.Is(err, ErrInvalidLength) {
+		t.Fatalf("extended token must not decode as plain token: %v", err)
+	}
+}
+
+func TestClaims_NoExpiry(t *testing.T) {
+	c := newCodecForTest(t, 0, 6, nil, nil)
+	s, err := c.EncodeWithClaims(7, Claims{})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	u, cl, err := c.DecodeWithClaims(s, nil)
+	if err != nil || u != 7 || !cl.ExpiresAt.IsZero() {
+		t.Fatalf("decode: u=%d cl=%+v err=%v", u, cl, err)
+	}
+}
+
+func TestClaims_ExpiryBounds(t *testing.T) {
+	c := newCodecForTest(t, 0, 6, nil, nil)
+	for _, exp := range []time.Time{time.Unix(0, 0), time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)} {
+		s, err := c.EncodeWithClaims(7, Claims{ExpiresAt: exp})
+		if err != nil {
+			t.Fatalf("%v: encode: %v", exp, err)
+		}
+		if _, cl, err := c.DecodeWithClaims(s, nil); !errors.Is(err, ErrExpired) || cl.ExpiresAt.IsZero() {
+			t.Fatalf("%v: want already expired, got %v %+v", exp, err, cl)
+		}
+	}
+
+	limit := int64(1)
+	for range expLen {
+		limit *= 62
+	}
+	if _, err := c.EncodeWithClaims(7, Claims{ExpiresAt: time.Unix(limit, 0)}); !errors.Is(err, ErrExpiryOutOfRange) {
+		t.Fatalf("want ErrExpiryOutOfRange, got %v", err)
+	}
+	s, err := c.EncodeWithClaims(7, Claims{ExpiresAt: time.Unix(limit-1, 0)})
+	if err != nil {
+		t.Fatalf("latest expiry: %v", err)
+	}
+	if _, cl, err := c.DecodeWithClaims(s, nil); err != nil || cl.ExpiresAt.Unix() != limit-1 {
+		t.Fatalf("latest expiry: %v %+v", err, cl)
+	}
+
+	crock := MustNewCodecFromSecret(Config{Secret: []byte("0123456789abcdefghijklmnop"), MacLen: 6, Encoding: EncodingCrockford32})
+	if _, err := crock.EncodeWithClaims(7, Claims{ExpiresAt: time.Unix(1<<35, 0)}); !errors.Is(err, ErrExpiryOutOfRange) {
+		t.Fatalf("crockford: want ErrExpiryOutOfRange, got %v", err)
+	}
+}
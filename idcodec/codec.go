@@ -3,11 +3,12 @@ package idcodec
 import (
 	"crypto/hmac"
 	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
 	"math/bits"
+	"sync"
 )
 
 const DefaultAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
@@ -16,17 +17,41 @@ var (
 	ErrInvalidLength     = errors.New("idcodec: invalid length")
 	ErrVersionMismatch   = errors.New("idcodec: version mismatch")
 	ErrMACVerification   = errors.New("idcodec: MAC verification failed")
-	ErrInvalidBase62Char = errors.New("idcodec: invalid base62 character")
+	ErrInvalidBase62Char = errors.New("idcodec: invalid base62 character") // returned for any encoding
 	ErrBadConfig         = errors.New("idcodec: bad config")
+	ErrKindMismatch      = errors.New("idcodec: kind mismatch")
+	ErrOverflow          = errors.New("idcodec: body overflows id width")
+)
+
+// Permutation selects how ids are scrambled before encoding. The zero value
+// keeps the original xor/rotate/add mixing so existing tokens keep decoding;
+// switch modes together with a new Config.Version and decode old tokens
+// through a Registry.
+type Permutation uint8
+
+const (
+	PermutationLegacy Permutation = iota // fixed-key xor/rotate/add, invertible from known pairs
+	PermutationSpeck                     // Speck64/128 block cipher keyed from Secret
 )
 
 type Config struct {
-	Secret   []byte
-	Version  uint8
-	MacLen   int
-	Alphabet string
-	Domain   []byte
-	Kind     *byte
+	Secret      []byte
+	Version     uint8
+	MacLen      int
+	Alphabet    string // overrides the encoding's default alphabet (not for crockford32)
+	Domain      []byte
+	Kind        *byte
+	Permutation Permutation
+	Encoding    Encoding
+	MinimalBody bool // drop leading zero digits from 64-bit bodies
+	CheckChar   bool // append a check char that tells typos from forgeries
+	KDF         KDF
+	Salt        []byte // HKDF salt, KDFHKDF only
+	Info        []byte // HKDF context, KDFHKDF only
+
+	// AllowWeakSecret skips CheckSecret, for legacy deployments whose tokens
+	// were minted with a short secret.
+	AllowWeakSecret bool
 }
 
 type Codec struct {
@@ -34,40 +59,38 @@ type Codec struct {
 	macLen   int
 	alphabet string
 	rev      [256]int8
+	radix    uint64
+	bodyLen  int  // digits in a full 64-bit body
+	uuidLen  int  // digits in a 128-bit body
+	macBits  uint // hash bits consumed per MAC char
+	minimal  bool
+	check    bool
 	k1, k2   uint64
 	k3, k4   uint64
 	macKey   [32]byte
 	domain   []byte
 	kind     *byte
+	perm     Permutation
+	speck    *speck64
+	macPool  sync.Pool
+	uk       [4]uint64 // 128-bit Feistel round tweaks
 }
 
 func NewCodecFromSecret(cfg Config) (*Codec, error) {
 	if cfg.MacLen <= 0 {
 		return nil, fmt.Errorf("%w: macLen must be > 0", ErrBadConfig)
 	}
-	if cfg.Version >= 62 {
-		return nil, fmt.Errorf("%w: version must be < 62", ErrBadConfig)
-	}
-	alp := cfg.Alphabet
-	if alp == "" {
-		alp = DefaultAlphabet
-	}
-	if len(alp) < 62 {
-		return nil, fmt.Errorf("%w: alphabet must have >=62 distinct chars", ErrBadConfig)
+	if cfg.Permutation > PermutationSpeck {
+		return nil, fmt.Errorf("%w: unknown permutation %d", ErrBadConfig, cfg.Permutation)
 	}
 
-	kMaster := sha256.Sum256(cfg.Secret)
-
-	derive := func(label byte) [32]byte {
-		h := sha256.New()
-		h.Write(kMaster[:])
-		h.Write([]byte{label})
-		var out [32]byte
-		copy(out[:], h.Sum(nil))
-		return out
+	derive, err := keyDeriver(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	kRaw := derive('K')
+	uRaw := derive('U')
 	k1 := binary.BigEndian.Uint64(kRaw[0:8])
 	k2 := binary.BigEndian.Uint64(kRaw[8:16])
 	k3 := binary.BigEndian.Uint64(kRaw[16:24])
@@ -75,22 +98,40 @@ func NewCodecFromSecret(cfg Config) (*Codec, error) {
 	mac := derive('M')
 
 	c := &Codec{
-		version:  cfg.Version,
-		macLen:   cfg.MacLen,
-		alphabet: alp,
-		k1:       k1,
-		k2:       k2,
-		k3:       k3,
-		k4:       k4,
-		macKey:   mac,
-		domain:   append([]byte(nil), cfg.Domain...),
-		kind:     cfg.Kind,
-	}
-	for i := range c.rev {
-		c.rev[i] = -1
-	}
-	for i := 0; i < len(alp) && i < 62; i++ {
-		c.rev[alp[i]] = int8(i)
+		version: cfg.Version,
+		macLen:  cfg.MacLen,
+		k1:      k1,
+		k2:      k2,
+		k3:      k3,
+		k4:      k4,
+		macKey:  mac,
+		domain:  append([]byte(nil), cfg.Domain...),
+		kind:    cfg.Kind,
+		perm:    cfg.Permutation,
+		check:   cfg.CheckChar,
+	}
+	if err := c.setupEncoding(cfg); err != nil {
+		return nil, err
+	}
+	if !cfg.AllowWeakSecret {
+		if err := CheckSecret(cfg.Secret); err != nil {
+			return nil, err
+		}
+	}
+	for i := range c.uk {
+		c.uk[i] = binary.BigEndian.Uint64(uRaw[i*8:])
+	}
+	c.macPool.New = func() any {
+		return &macState{h: hmac.New(sha256.New, c.macKey[:]), exp: make([]byte, 0, c.macLen)}
+	}
+	if cfg.Permutation == PermutationSpeck {
+		sk := derive('S')
+		c.speck = newSpeck64([4]uint32{
+			binary.BigEndian.Uint32(sk[0:4]),
+			binary.BigEndian.Uint32(sk[4:8]),
+			binary.BigEndian.Uint32(sk[8:12]),
+			binary.BigEndian.Uint32(sk[12:16]),
+		})
 	}
 	return c, nil
 }
@@ -107,39 +148,34 @@ func MustNewCodecFromSecret(cfg Config) *Codec {
 	return c
 }
 
-func (c *Codec) base62EncodeFixed(u uint64) string {
-	var buf [11]byte
-	base := uint64(62)
-	for i := 10; i >= 0; i-- {
-		buf[i] = c.alphabet[u%base]
-		u /= base
+func (c *Codec) appendBody(dst []byte, u uint64) []byte {
+	if c.minimal {
+		return c.appendMinimal(dst, u)
 	}
-	return string(buf[:])
+	return c.appendDigits(dst, u, c.bodyLen)
 }
 
-func (c *Codec) base62DecodeFixed(s string) (uint64, error) {
-	if len(s) != 11 {
-		return 0, ErrInvalidLength
+// bodyLenOf returns the body length of a 64-bit token of n bytes.
+func (c *Codec) bodyLenOf(n int) (int, bool) {
+	b := n - 1 - c.macLen
+	if c.minimal {
+		return b, b >= 1 && b <= c.bodyLen
 	}
-	var u uint64
-	base := uint64(62)
-	for i := 0; i < 11; i++ {
-		v := c.rev[s[i]]
-		if v < 0 {
-			return 0, ErrInvalidBase62Char
-		}
-		u = u*base + uint64(v)
-	}
-	return u, nil
+	return b, b == c.bodyLen
 }
 
-func toBytes8(u uint64) [8]byte {
-	var b [8]byte
-	binary.BigEndian.PutUint64(b[:], u)
-	return b
+// decodeBody reads the n-digit body starting at off.
+func decodeBody[T string | []byte](c *Codec, s T, off, n int) (uint64, error) {
+	if c.minimal && n > 1 && c.rev[s[off]] == 0 {
+		return 0, ErrInvalidLength // non-canonical leading zero
+	}
+	return decodeDigits(c, s, off, n)
 }
 
 func (c *Codec) permutation(x uint64) uint64 {
+	if c.speck != nil {
+		return c.speck.encrypt(x)
+	}
 	x ^= c.k1
 	x = bits.RotateLeft64(x, 17)
 	x += c.k2
@@ -150,6 +186,9 @@ func (c *Codec) permutation(x uint64) uint64 {
 }
 
 func (c *Codec) inversePermutation(x uint64) uint64 {
+	if c.speck != nil {
+		return c.speck.decrypt(x)
+	}
 	x -= c.k4
 	x ^= c.k3
 	x = bits.RotateLeft64(x, -31)
@@ -159,50 +198,115 @@ func (c *Codec) inversePermutation(x uint64) uint64 {
 	return x
 }
 
-func (c *Codec) hmacPadding(encrypted uint64, kind *byte) string {
-	h := hmac.New(sha256.New, c.macKey[:])
+// macState is pooled per codec so hot paths reuse the HMAC state and
+// scratch buffers instead of allocating them per token.
+type macState struct {
+	h   hash.Hash
+	buf [32]byte // header at [0:4], 64-bit body at [16:24]
+	sum [sha256.Size]byte
+	exp []byte
+}
+
+func (c *Codec) getMAC() *macState {
+	return c.macPool.Get().(*macState)
+}
+
+// macSum authenticates the encrypted body bytes under the codec's domain,
+// kind, mode and version.
+func (c *Codec) macSum(st *macState, body []byte, kind *byte) []byte {
+	h := st.h
+	h.Reset()
 	if len(c.domain) > 0 {
 		h.Write(c.domain)
 	}
+	hdr := st.buf[:0]
 	if kind != nil {
-		h.Write([]byte{*kind})
+		hdr = append(hdr, *kind)
+	}
+	if c.perm != PermutationLegacy {
+		// bind the mode so a token never verifies under another permutation
+		hdr = append(hdr, 'P', byte(c.perm))
 	}
-	h.Write([]byte{c.alphabet[c.version]})
-	b8 := toBytes8(encrypted)
-	h.Write(b8[:])
-	sum := h.Sum(nil)
+	hdr = append(hdr, c.versionChar())
+	h.Write(hdr)
+	h.Write(body)
+	return h.Sum(st.sum[:0])
+}
 
-	out := make([]byte, c.macLen)
+// appendMACChars renders c.macLen alphabet chars, c.macBits of sum each;
+// values past the radix fold back into range.
+func (c *Codec) appendMACChars(dst []byte, sum []byte) []byte {
 	var bitbuf uint64
 	var bitsIn uint
-	idx := 0
+	nb := c.macBits
+	mask := uint64(1)<<nb - 1
+	fold := mask + 1 - c.radix
 	src := 0
-	for idx < c.macLen {
-		for bitsIn < 6 && src < len(sum) {
+	for idx := 0; idx < c.macLen; idx++ {
+		for bitsIn < nb && src < len(sum) {
 			bitbuf = (bitbuf << 8) | uint64(sum[src])
 			bitsIn += 8
 			src++
 		}
-		if bitsIn < 6 {
-			bitbuf <<= (6 - bitsIn)
-			bitsIn = 6
+		if bitsIn < nb {
+			bitbuf <<= (nb - bitsIn)
+			bitsIn = nb
 		}
-		shift := bitsIn - 6
-		val := (bitbuf >> shift) & 0x3F
-		bitsIn -= 6
-		if val >= 62 {
-			val -= 2
+		shift := bitsIn - nb
+		val := (bitbuf >> shift) & mask
+		bitsIn -= nb
+		if val >= c.radix {
+			val -= fold
 		}
-		out[idx] = c.alphabet[val]
-		idx++
+		dst = append(dst, c.alphabet[val])
 	}
+	return dst
+}
+
+func (c *Codec) appendMAC64(dst []byte, enc uint64, kind *byte) []byte {
+	st := c.getMAC()
+	binary.BigEndian.PutUint64(st.buf[16:24], enc)
+	dst = c.appendMACChars(dst, c.macSum(st, st.buf[16:24], kind))
+	c.macPool.Put(st)
+	return dst
+}
+
+// macChars is the allocating form used by the 128-bit and claims tokens.
+func (c *Codec) macChars(body []byte, kind *byte) string {
+	st := c.getMAC()
+	out := c.appendMACChars(make([]byte, 0, c.macLen), c.macSum(st, body, kind))
+	c.macPool.Put(st)
 	return string(out)
 }
 
-func (c *Codec) token(enc uint64, kind *byte) string {
-	body := c.base62EncodeFixed(enc)
-	pad := c.hmacPadding(enc, kind)
-	return string([]byte{c.alphabet[c.version]}) + body + pad
+// verifyMAC64 compares the MAC chars of s at off against enc in constant time.
+func verifyMAC64[T string | []byte](c *Codec, s T, off int, enc uint64, kind *byte) bool {
+	st := c.getMAC()
+	binary.BigEndian.PutUint64(st.buf[16:24], enc)
+	st.exp = c.appendMACChars(st.exp[:0], c.macSum(st, st.buf[16:24], kind))
+	ok := macEqual(c, s, off, st.exp)
+	c.macPool.Put(st)
+	return ok
+}
+
+func (c *Codec) appendToken(dst []byte, enc uint64, kind *byte) []byte {
+	start := len(dst)
+	dst = append(dst, c.versionChar())
+	dst = c.appendBody(dst, enc)
+	dst = c.appendMAC64(dst, enc, kind)
+	if c.check {
+		dst = c.appendCheck(dst, start)
+	}
+	return dst
+}
+
+// tokenLen is the length of a full-width 64-bit token.
+func (c *Codec) tokenLen() int {
+	n := 1 + c.bodyLen + c.macLen
+	if c.check {
+		n++
+	}
+	return n
 }
 
 func (c *Codec) EncodeUint64(id uint64) string {
@@ -210,8 +314,52 @@ func (c *Codec) EncodeUint64(id uint64) string {
 }
 
 func (c *Codec) EncodeUint64WithKind(id uint64, kind *byte) string {
-	enc := c.permutation(id)
-	return c.token(enc, kind)
+	var buf [64]byte
+	return string(c.appendToken(buf[:0], c.permutation(id), kind))
+}
+
+// AppendEncode appends the token for id to dst; it does not allocate when
+// dst has room for the token.
+func (c *Codec) AppendEncode(dst []byte, id uint64) []byte {
+	return c.appendToken(dst, c.permutation(id), c.kind)
+}
+
+// DecodeBytes is DecodeToUint64 for a token held in a byte slice, without
+// converting it to a string.
+func (c *Codec) DecodeBytes(b []byte) (uint64, error) {
+	return decodeToken(c, b, true, c.kind)
+}
+
+// EncodeMany encodes ids into tokens that share a single backing buffer.
+func (c *Codec) EncodeMany(ids []uint64) []string {
+	buf := make([]byte, 0, c.tokenLen()*len(ids))
+	ends := make([]int, len(ids))
+	for i, id := range ids {
+		buf = c.AppendEncode(buf, id)
+		ends[i] = len(buf)
+	}
+	all := string(buf)
+	out := make([]string, len(ids))
+	start := 0
+	for i, end := range ends {
+		out[i] = all[start:end]
+		start = end
+	}
+	return out
+}
+
+// DecodeMany decodes tokens in order and stops at the first invalid one,
+// reporting its index.
+func (c *Codec) DecodeMany(tokens []string) ([]uint64, error) {
+	out := make([]uint64, len(tokens))
+	for i, s := range tokens {
+		u, err := c.DecodeToUint64(s)
+		if err != nil {
+			return nil, fmt.Errorf("idcodec: token %d: %w", i, err)
+		}
+		out[i] = u
+	}
+	return out, nil
 }
 
 func (c *Codec) Validate(s string) error {
@@ -227,23 +375,79 @@ func (c *Codec) DecodeToUint64WithKind(s string, kind *byte) (uint64, error) {
 	return c.decodeInternal(s, true, kind)
 }
 
+// DecodeToUint64ExpectKind decodes s for kind. If the MAC fails for kind but
+// verifies for one of others, ErrKindMismatch is returned instead of
+// ErrMACVerification, telling a token minted for another kind from a forgery.
+func (c *Codec) DecodeToUint64ExpectKind(s string, kind *byte, others ...*byte) (uint64, error) {
+	u, err := c.decodeInternal(s, true, kind)
+	if !errors.Is(err, ErrMACVerification) {
+		return u, err
+	}
+	for _, o := range others {
+		if sameKind(o, kind) {
+			continue
+		}
+		if _, e := c.decodeInternal(s, false, o); e == nil {
+			return 0, ErrKindMismatch
+		}
+	}
+	return 0, err
+}
+
+func (c *Codec) Version() uint8 {
+	return c.version
+}
+
+func (c *Codec) versionChar() byte {
+	return c.alphabet[c.version]
+}
+
+// HasVersion reports whether s starts with this codec's version char, in any
+// spelling the encoding accepts (e.g. lowercase for Crockford base32).
+func (c *Codec) HasVersion(s string) bool {
+	return len(s) > 0 && c.rev[s[0]] == int8(c.version)
+}
+
+// Kind returns the default kind from Config.Kind, or nil.
+func (c *Codec) Kind() *byte {
+	return c.kind
+}
+
+func sameKind(a, b *byte) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func (c *Codec) decodeInternal(s string, needValue bool, kind *byte) (uint64, error) {
-	need := 1 + 11 + c.macLen
-	if len(s) != need {
+	return decodeToken(c, s, needValue, kind)
+}
+
+func decodeToken[T string | []byte](c *Codec, s T, needValue bool, kind *byte) (uint64, error) {
+	if c.check {
+		if _, ok := c.bodyLenOf(len(s) - 1); !ok {
+			return 0, ErrInvalidLength
+		}
+		p, err := verifyCheck(c, s)
+		if err != nil {
+			return 0, err
+		}
+		s = s[:p]
+	}
+	n, ok := c.bodyLenOf(len(s))
+	if !ok {
 		return 0, ErrInvalidLength
 	}
 	verCh := s[0]
 	if c.rev[verCh] != int8(c.version) {
 		return 0, ErrVersionMismatch
 	}
-	body := s[1 : 1+11]
-	enc, err := c.base62DecodeFixed(body)
+	enc, err := decodeBody(c, s, 1, n)
 	if err != nil {
 		return 0, err
 	}
-	pad := s[1+11:]
-	exp := c.hmacPadding(enc, kind)
-	if subtle.ConstantTimeCompare([]byte(pad), []byte(exp)) != 1 {
+	if !verifyMAC64(c, s, 1+n, enc, kind) {
 		return 0, ErrMACVerification
 	}
 	if !needValue {
@@ -253,11 +457,20 @@ func (c *Codec) decodeInternal(s string, needValue bool, kind *byte) (uint64, er
 }
 
 func (c *Codec) DecodeBodyOnly(s string) (uint64, error) {
-	if len(s) < 1+11 {
+	if c.check && len(s) > 0 {
+		s = s[:len(s)-1]
+	}
+	n := c.bodyLen
+	if c.minimal {
+		n = len(s) - 1 - c.macLen
+		if n < 1 || n > c.bodyLen {
+			return 0, ErrInvalidLength
+		}
+	}
+	if len(s) < 1+n {
 		return 0, ErrInvalidLength
 	}
-	body := s[1 : 1+11]
-	return c.base62DecodeFixed(body)
+	return decodeBody(c, s, 1, n)
 }
 
 func (c *Codec) MustEncodeUint64(id uint64) string {
@@ -272,6 +485,8 @@ func (c *Codec) MustDecodeToUint64(s string) uint64 {
 	return u
 }
 
+// MultiCodec tries Cur, then each Old codec in turn. Prefer Registry, which
+// dispatches on the version char and reports why a token was rejected.
 type MultiCodec struct {
 	Cur *Codec
 	Old []*Codec
@@ -292,3 +507,8 @@ func (m MultiCodec) DecodeToUint64(s string) (uint64, error) {
 	}
 	return 0, ErrMACVerification
 }
+
+func (m MultiCodec) Validate(s string) error {
+	_, err := m.DecodeToUint64(s)
+	return err
+}
@@ -0,0 +1,98 @@
+package idcodec
+
+import "testing"
+
+func benchCodec(b testing.TB) *Codec {
+	return MustNewCodecFromSecret(Config{Secret: []byte("bench-secret"), Version: 1, MacLen: 8, Domain: []byte("bench"), AllowWeakSecret: true})
+}
+
+func TestAppendEncodeAndDecodeBytes_ZeroAlloc(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable under the race detector")
+	}
+	c := benchCodec(t)
+	buf := make([]byte, 0, 64)
+	tok := c.AppendEncode(nil, 1234567890)
+	// warm the HMAC pool
+	c.AppendEncode(buf, 1)
+	if n := testing.AllocsPerRun(100, func() { c.AppendEncode(buf[:0], 1234567890) }); n != 0 {
+		t.Fatalf("AppendEncode allocs = %v, want 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { _, _ = c.DecodeBytes(tok) }); n != 0 {
+		t.Fatalf("DecodeBytes allocs = %v, want 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { _ = c.EncodeUint64(1234567890) }); n > 1 {
+		t.Fatalf("EncodeUint64 allocs = %v, want <= 1", n)
+	}
+}
+
+func TestEncodeManyDecodeMany(t *testing.T) {
+	c := benchCodec(t)
+	ids := []uint64{0, 1, 42, 1 << 63}
+	toks := c.EncodeMany(ids)
+	for i, s := range toks {
+		if s != c.EncodeUint64(ids[i]) {
+			t.Fatalf("token %d differs from EncodeUint64", i)
+		}
+	}
+	got, err := c.DecodeMany(toks)
+	if err != nil {
+		t.Fatalf("DecodeMany: %v", err)
+	}
+	for i := range ids {
+		if got[i] != ids[i] {
+			t.Fatalf("id %d mismatch", i)
+		}
+	}
+	toks[2] = "bad"
+	if _, err := c.DecodeMany(toks); err == nil {
+		t.Fatalf("expected error for bad token")
+	}
+}
+
+func BenchmarkEncodeUint64(b *testing.B) {
+	c := benchCodec(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = c.EncodeUint64(uint64(i))
+	}
+}
+
+func BenchmarkAppendEncode(b *testing.B) {
+	c := benchCodec(b)
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = c.AppendEncode(buf[:0], uint64(i))
+	}
+}
+
+func BenchmarkDecodeToUint64(b *testing.B) {
+	c := benchCodec(b)
+	s := c.EncodeUint64(1234567890)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = c.DecodeToUint64(s)
+	}
+}
+
+func BenchmarkDecodeBytes(b *testing.B) {
+	c := benchCodec(b)
+	tok := c.AppendEncode(nil, 1234567890)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = c.DecodeBytes(tok)
+	}
+}
+
+func BenchmarkEncodeMany1000(b *testing.B) {
+	c := benchCodec(b)
+	ids := make([]uint64, 1000)
+	for i := range ids {
+		ids[i] = uint64(i)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = c.EncodeMany(ids)
+	}
+}
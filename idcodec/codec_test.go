@@ -94,9 +94,7 @@ func TestValidate_SuccessAndFail(t *testing.T) {
 	if err := c.Validate(s); err != nil {
 		t.Fatalf("validate failed")
 	}
-	b := []byte(s)
-	b[len(b)-1] = c.alphabet[(int(b[len(b)-1])%62+1)%62]
-	if err := c.Validate(string(b)); !errors.Is(err, ErrMACVerification) {
+	if err := c.Validate(tamperLast(c, s)); !errors.Is(err, ErrMACVerification) {
 		t.Fatalf("validate should fail")
 	}
 }
@@ -104,9 +102,7 @@ func TestValidate_SuccessAndFail(t *testing.T) {
 func TestDecode_MACMismatch_OnPadTamper(t *testing.T) {
 	c := newCodecForTest(t, 0, 6, nil, nil)
 	s := c.EncodeUint64(777)
-	b := []byte(s)
-	b[len(b)-1] = c.alphabet[(int(b[len(b)-1])%62+7)%62]
-	if _, err := c.DecodeToUint64(string(b)); !errors.Is(err, ErrMACVerification) {
+	if _, err := c.DecodeToUint64(tamperLast(c, s)); !errors.Is(err, ErrMACVerification) {
 		t.Fatalf("expected MAC failure")
 	}
 }
@@ -182,8 +178,8 @@ func TestKindAffectsToken_AndValidation(t *testing.T) {
 
 func TestDomainAffectsToken(t *testing.T) {
 	secret := []byte("app-secret")
-	c1 := MustNewCodecFromSecret(Config{Secret: secret, Version: 0, MacLen: 6, Domain: []byte("A")})
-	c2 := MustNewCodecFromSecret(Config{Secret: secret, Version: 0, MacLen: 6, Domain: []byte("B")})
+	c1 := MustNewCodecFromSecret(Config{Secret: secret, Version: 0, MacLen: 6, Domain: []byte("A"), AllowWeakSecret: true})
+	c2 := MustNewCodecFromSecret(Config{Secret: secret, Version: 0, MacLen: 6, Domain: []byte("B"), AllowWeakSecret: true})
 	id := uint64(9090)
 	s1 := c1.EncodeUint64(id)
 	s2 := c2.EncodeUint64(id)
@@ -197,7 +193,7 @@ func TestDomainAffectsToken(t *testing.T) {
 
 func TestAlphabetOverride_Works(t *testing.T) {
 	alp := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
-	c := MustNewCodecFromSecret(Config{Secret: []byte("k"), Version: 1, MacLen: 5, Alphabet: alp})
+	c := MustNewCodecFromSecret(Config{Secret: []byte("k"), Version: 1, MacLen: 5, Alphabet: alp, AllowWeakSecret: true})
 	id := uint64(424242)
 	s := c.EncodeUint64(id)
 	u, err := c.DecodeToUint64(s)
@@ -259,3 +255,26 @@ func TestMultiCodec_DecodeOld(t *testing.T) {
 		t.Fatalf("multi decode current failed")
 	}
 }
+
+func TestDecodeExpectKind_DistinguishesKindFromForgery(t *testing.T) {
+	u, o := byte('U'), byte('O')
+	c := newCodecForTest(t, 0, 6, nil, nil)
+	s := c.EncodeUint64WithKind(77, &u)
+	if v, err := c.DecodeToUint64ExpectKind(s, &u, &o); err != nil || v != 77 {
+		t.Fatalf("decode same kind failed: %v", err)
+	}
+	if _, err := c.DecodeToUint64ExpectKind(s, &o, &u, nil); !errors.Is(err, ErrKindMismatch) {
+		t.Fatalf("expected kind mismatch, got %v", err)
+	}
+	if _, err := c.DecodeToUint64ExpectKind(tamperLast(c, s), &o, &u, nil); !errors.Is(err, ErrMACVerification) {
+		t.Fatalf("expected MAC failure for tampered token, got %v", err)
+	}
+}
+
+// tamperLast swaps the last char for the next one in the alphabet, which is
+// guaranteed to differ from the original.
+func tamperLast(c *Codec, s string) string {
+	b := []byte(s)
+	b[len(b)-1] = c.alphabet[(int(c.rev[b[len(b)-1]])+1)%62]
+	return string(b)
+}
@@ -0,0 +1,160 @@
+package idcodec
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Encoding selects the token alphabet. Every encoding uses the same
+// version/body/MAC layout; only the radix and the chars differ.
+type Encoding uint8
+
+const (
+	EncodingBase62      Encoding = iota // case-sensitive, the original format
+	EncodingBase58                      // no 0/O/I/l look-alikes, still case-sensitive
+	EncodingCrockford32                 // case-insensitive, safe to read aloud
+)
+
+const (
+	Base58Alphabet    = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	CrockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// crockfordAliases maps the chars Crockford base32 accepts on decode to the
+// digit they stand for; lowercase letters are added on top.
+var crockfordAliases = map[byte]byte{'O': '0', 'I': '1', 'L': '1'}
+
+func (e Encoding) radix() int {
+	switch e {
+	case EncodingBase58:
+		return 58
+	case EncodingCrockford32:
+		return 32
+	default:
+		return 62
+	}
+}
+
+func (e Encoding) defaultAlphabet() string {
+	switch e {
+	case EncodingBase58:
+		return Base58Alphabet
+	case EncodingCrockford32:
+		return CrockfordAlphabet
+	default:
+		return DefaultAlphabet
+	}
+}
+
+// digitsFor returns how many radix digits are needed for a w-bit value.
+func digitsFor(radix, w int) int {
+	return int(math.Ceil(float64(w) / math.Log2(float64(radix))))
+}
+
+// setupEncoding validates the alphabet for cfg and fills the radix-dependent
+// fields of c.
+func (c *Codec) setupEncoding(cfg Config) error {
+	if cfg.Encoding > EncodingCrockford32 {
+		return fmt.Errorf("%w: unknown encoding %d", ErrBadConfig, cfg.Encoding)
+	}
+	radix := cfg.Encoding.radix()
+	alp := cfg.Alphabet
+	if alp == "" {
+		alp = cfg.Encoding.defaultAlphabet()
+	} else if cfg.Encoding == EncodingCrockford32 {
+		return fmt.Errorf("%w: crockford32 uses a fixed alphabet", ErrBadConfig)
+	}
+	if len(alp) < radix {
+		return fmt.Errorf("%w: alphabet must have >=%d distinct chars", ErrBadConfig, radix)
+	}
+	alp = alp[:radix]
+	if int(cfg.Version) >= radix {
+		return fmt.Errorf("%w: version must be < %d", ErrBadConfig, radix)
+	}
+
+	for i := range c.rev {
+		c.rev[i] = -1
+	}
+	for i := 0; i < radix; i++ {
+		ch := alp[i]
+		if ch <= ' ' || ch >= 0x7f {
+			return fmt.Errorf("%w: alphabet char %q is not printable ASCII", ErrBadConfig, ch)
+		}
+		c.rev[ch] = int8(i)
+	}
+	if cfg.Encoding == EncodingCrockford32 {
+		for i := 0; i < radix; i++ {
+			if ch := alp[i]; ch >= 'A' && ch <= 'Z' {
+				c.rev[ch+'a'-'A'] = int8(i)
+			}
+		}
+		for alias, digit := range crockfordAliases {
+			c.rev[alias] = c.rev[digit]
+			c.rev[alias+'a'-'A'] = c.rev[digit]
+		}
+	}
+
+	c.alphabet = alp
+	c.radix = uint64(radix)
+	c.bodyLen = digitsFor(radix, 64)
+	c.uuidLen = digitsFor(radix, 128)
+	c.macBits = uint(bits.Len(uint(radix - 1)))
+	c.minimal = cfg.MinimalBody
+	return nil
+}
+
+// appendDigits appends u as exactly n digits, most significant first.
+func (c *Codec) appendDigits(dst []byte, u uint64, n int) []byte {
+	var buf [64]byte
+	for i := n - 1; i >= 0; i-- {
+		buf[i] = c.alphabet[u%c.radix]
+		u /= c.radix
+	}
+	return append(dst, buf[:n]...)
+}
+
+// appendMinimal appends u without leading zero digits.
+func (c *Codec) appendMinimal(dst []byte, u uint64) []byte {
+	var buf [64]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = c.alphabet[u%c.radix]
+		u /= c.radix
+		if u == 0 {
+			break
+		}
+	}
+	return append(dst, buf[i:]...)
+}
+
+// decodeDigits reads s[off:off+n] as a radix number, rejecting values that
+// do not fit in 64 bits so no two strings decode to the same value.
+func decodeDigits[T string | []byte](c *Codec, s T, off, n int) (uint64, error) {
+	var u uint64
+	for i := off; i < off+n; i++ {
+		v := c.rev[s[i]]
+		if v < 0 {
+			return 0, ErrInvalidBase62Char
+		}
+		hi, lo := bits.Mul64(u, c.radix)
+		lo, carry := bits.Add64(lo, uint64(v), 0)
+		if hi != 0 || carry != 0 {
+			return 0, ErrOverflow
+		}
+		u = lo
+	}
+	return u, nil
+}
+
+// macEqual compares the MAC chars of s at off with exp by digit value, so
+// case-insensitive encodings accept any spelling; it runs in constant time
+// for a given length.
+func macEqual[T string | []byte, E string | []byte](c *Codec, s T, off int, exp E) bool {
+	var diff int8
+	for i := 0; i < len(exp); i++ {
+		diff |= c.rev[s[off+i]] ^ c.rev[exp[i]]
+	}
+	return diff == 0
+}
@@ -0,0 +1,99 @@
+package idcodec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodings_RoundTrip(t *testing.T) {
+	cases := []struct {
+		enc     Encoding
+		bodyLen int
+	}{
+		{EncodingBase62, 11},
+		{EncodingBase58, 11},
+		{EncodingCrockford32, 13},
+	}
+	for _, tc := range cases {
+		c := MustNewCodecFromSecret(Config{Secret: []byte("enc"), Version: 5, MacLen: 6, Encoding: tc.enc, AllowWeakSecret: true})
+		for _, id := range []uint64{0, 1, 1234567890, 18446744073709551615} {
+			s := c.EncodeUint64(id)
+			if len(s) != 1+tc.bodyLen+6 {
+				t.Fatalf("encoding %d: token %q has length %d", tc.enc, s, len(s))
+			}
+			for i := 0; i < len(s); i++ {
+				if !strings.ContainsRune(c.alphabet, rune(s[i])) {
+					t.Fatalf("encoding %d: char %q outside alphabet", tc.enc, s[i])
+				}
+			}
+			if u, err := c.DecodeToUint64(s); err != nil || u != id {
+				t.Fatalf("encoding %d: round-trip %d: %v", tc.enc, id, err)
+			}
+			if u, err := c.DecodeUUID(c.EncodeUUID([16]byte{1, 2, 3})); err != nil || u != [16]byte{1, 2, 3} {
+				t.Fatalf("encoding %d: uuid round-trip: %v", tc.enc, err)
+			}
+		}
+	}
+}
+
+func TestCrockford_CaseInsensitiveAndAliases(t *testing.T) {
+	c := MustNewCodecFromSecret(Config{Secret: []byte("phone"), Version: 1, MacLen: 5, Encoding: EncodingCrockford32, AllowWeakSecret: true})
+	id := uint64(987654321)
+	s := c.EncodeUint64(id)
+	if u, err := c.DecodeToUint64(strings.ToLower(s)); err != nil || u != id {
+		t.Fatalf("lowercase token should decode: %v", err)
+	}
+	typed := strings.NewReplacer("0", "o", "1", "L").Replace(s)
+	if u, err := c.DecodeToUint64(typed); err != nil || u != id {
+		t.Fatalf("aliased token %q should decode: %v", typed, err)
+	}
+	if _, err := c.DecodeToUint64(strings.Replace(s, s[3:4], "U", 1)); err == nil {
+		t.Fatalf("U is not a crockford digit")
+	}
+	if !c.HasVersion(s) || !c.HasVersion("l"+s[1:]) || c.HasVersion("2"+s[1:]) || c.HasVersion("") {
+		t.Fatalf("HasVersion mismatch")
+	}
+}
+
+func TestMinimalBody(t *testing.T) {
+	c := MustNewCodecFromSecret(Config{Secret: []byte("min"), MacLen: 4, MinimalBody: true, AllowWeakSecret: true})
+	for _, id := range []uint64{0, 1, 42, 1 << 40, 18446744073709551615} {
+		s := c.EncodeUint64(id)
+		if u, err := c.DecodeToUint64(s); err != nil || u != id {
+			t.Fatalf("round-trip %d: %v", id, err)
+		}
+		if s[1] == c.alphabet[0] && len(s) > 1+1+4 {
+			t.Fatalf("minimal body has a leading zero: %q", s)
+		}
+		padded := s[:1] + c.alphabet[:1] + s[1:]
+		if len(padded) <= 1+c.bodyLen+4 {
+			if _, err := c.DecodeToUint64(padded); err == nil {
+				t.Fatalf("non-canonical body should be rejected")
+			}
+		}
+	}
+}
+
+func TestEncodingConfigErrors(t *testing.T) {
+	bad := []Config{
+		{Secret: []byte("x"), MacLen: 4, Encoding: EncodingCrockford32, Version: 32},
+		{Secret: []byte("x"), MacLen: 4, Encoding: EncodingCrockford32, Alphabet: CrockfordAlphabet},
+		{Secret: []byte("x"), MacLen: 4, Encoding: EncodingBase58, Alphabet: "abc"},
+		{Secret: []byte("x"), MacLen: 4, Encoding: 9},
+	}
+	for i, cfg := range bad {
+		if _, err := NewCodecFromSecret(cfg); !errors.Is(err, ErrBadConfig) {
+			t.Fatalf("case %d: expected ErrBadConfig, got %v", i, err)
+		}
+	}
+}
+
+func TestDecode_OverflowRejected(t *testing.T) {
+	c := newCodecForTest(t, 0, 6, nil, nil)
+	s := c.EncodeUint64(1)
+	over := s[:1] + strings.Repeat("z", 11) + s[12:]
+	if _, err := c.DecodeToUint64(over); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected overflow, got %v", err)
+	}
+}
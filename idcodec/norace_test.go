@@ -0,0 +1,5 @@
+//go:build !race
+
+package idcodec
+
+const raceEnabled = false
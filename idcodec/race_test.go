@@ -0,0 +1,7 @@
+//go:build race
+
+package idcodec
+
+// raceEnabled skips allocation checks: sync.Pool drops items at random under
+// the race detector.
+const raceEnabled = true
@@ -0,0 +1,192 @@
+package idcodec
+
+import "fmt"
+
+// Registry dispatches tokens to a codec by their version char, so keys can be
+// rotated by adding a codec with a new Config.Version and keeping the old ones
+// for decoding. New tokens are always minted by the current codec.
+type Registry struct {
+	cur   *Codec
+	byVer [256]*Codec // indexed by version char, aliases and other cases included
+	byNum [256]*Codec // indexed by Config.Version, first registered wins
+	all   []*Codec
+}
+
+func NewRegistry(cur *Codec, old ...*Codec) (*Registry, error) {
+	if cur == nil {
+		return nil, fmt.Errorf("%w: current codec is nil", ErrBadConfig)
+	}
+	r := &Registry{cur: cur}
+	for _, c := range append([]*Codec{cur}, old...) {
+		if c == nil {
+			return nil, fmt.Errorf("%w: codec is nil", ErrBadConfig)
+		}
+		ch := c.versionChar()
+		if r.byVer[ch] != nil {
+			return nil, fmt.Errorf("%w: duplicate version char %q", ErrBadConfig, ch)
+		}
+		r.byVer[ch] = c
+		if r.byNum[c.version] == nil {
+			r.byNum[c.version] = c
+		}
+		r.all = append(r.all, c)
+	}
+	// Route every other spelling of a version char, e.g. lowercase or O for 0
+	// in Crockford base32, unless it is some codec's canonical char.
+	for _, c := range r.all {
+		for ch := range r.byVer {
+			if r.byVer[ch] == nil && c.rev[ch] == int8(c.version) {
+				r.byVer[ch] = c
+			}
+		}
+	}
+	return r, nil
+}
+
+func MustNewRegistry(cur *Codec, old ...*Codec) *Registry {
+	r, err := NewRegistry(cur, old...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Current returns the codec new tokens are encoded with.
+func (r *Registry) Current() *Codec {
+	return r.cur
+}
+
+// Codec returns the codec registered for version, or nil.
+func (r *Registry) Codec(version uint8) *Codec {
+	return r.byNum[version]
+}
+
+// Kind returns the default kind of the current codec.
+func (r *Registry) Kind() *byte {
+	return r.cur.kind
+}
+
+func (r *Registry) lookup(s string) (*Codec, error) {
+	if len(s) == 0 {
+		return nil, ErrInvalidLength
+	}
+	c := r.byVer[s[0]]
+	if c == nil {
+		return nil, ErrVersionMismatch
+	}
+	return c, nil
+}
+
+func (r *Registry) EncodeUint64(id uint64) string {
+	return r.cur.EncodeUint64(id)
+}
+
+func (r *Registry) EncodeUint64WithKind(id uint64, kind *byte) string {
+	return r.cur.EncodeUint64WithKind(id, kind)
+}
+
+func (r *Registry) DecodeToUint64(s string) (uint64, error) {
+	u, _, err := r.DecodeVersion(s)
+	return u, err
+}
+
+// DecodeVersion decodes s and reports the version of the codec it was
+// dispatched to, also when that codec rejects it.
+func (r *Registry) DecodeVersion(s string) (uint64, uint8, error) {
+	c, err := r.lookup(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	u, err := c.DecodeToUint64(s)
+	return u, c.version, err
+}
+
+func (r *Registry) DecodeToUint64WithKind(s string, kind *byte) (uint64, error) {
+	c, err := r.lookup(s)
+	if err != nil {
+		return 0, err
+	}
+	return c.DecodeToUint64WithKind(s, kind)
+}
+
+func (r *Registry) DecodeToUint64ExpectKind(s string, kind *byte, others ...*byte) (uint64, error) {
+	c, err := r.lookup(s)
+	if err != nil {
+		return 0, err
+	}
+	return c.DecodeToUint64ExpectKind(s, kind, others...)
+}
+
+func (r *Registry) Validate(s string) error {
+	c, err := r.lookup(s)
+	if err != nil {
+		return err
+	}
+	return c.Validate(s)
+}
+
+// NeedsReencode reports whether s is a valid token minted by a codec other
+// than the current one, i.e. a candidate for lazy migration.
+func (r *Registry) NeedsReencode(s string) bool {
+	c, err := r.lookup(s)
+	if err != nil || c == r.cur {
+		return false
+	}
+	return c.Validate(s) == nil
+}
+
+// Reencode decodes s with whichever codec minted it and re-encodes the id
+// with the current codec. Tokens already current are returned unchanged.
+func (r *Registry) Reencode(s string) (string, error) {
+	c, err := r.lookup(s)
+	if err != nil {
+		return "", err
+	}
+	if c == r.cur {
+		return s, c.Validate(s)
+	}
+	u, err := c.DecodeToUint64(s)
+	if err != nil {
+		return "", err
+	}
+	return r.cur.EncodeUint64(u), nil
+}
+
+func (r *Registry) EncodeUUID(u [16]byte) string {
+	return r.cur.EncodeUUID(u)
+}
+
+func (r *Registry) DecodeUUID(s string) ([16]byte, error) {
+	c, err := r.lookup(s)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	return c.DecodeUUID(s)
+}
+
+func (r *Registry) EncodeWithClaims(id uint64, cl Claims) (string, error) {
+	return r.cur.EncodeWithClaims(id, cl)
+}
+
+func (r *Registry) DecodeWithClaims(s string, audience []byte) (uint64, Claims, error) {
+	c, err := r.lookup(s)
+	if err != nil {
+		return 0, Claims{}, err
+	}
+	return c.DecodeWithClaims(s, audience)
+}
+
+func (r *Registry) AppendEncode(dst []byte, id uint64) []byte {
+	return r.cur.AppendEncode(dst, id)
+}
+
+func (r *Registry) DecodeBytes(b []byte) (uint64, error) {
+	if len(b) == 0 {
+		return 0, ErrInvalidLength
+	}
+	c := r.byVer[b[0]]
+	if c == nil {
+		return 0, ErrVersionMismatch
+	}
+	return c.DecodeBytes(b)
+}
@@ -0,0 +1,96 @@
+package idcodec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_DispatchByVersion(t *testing.T) {
+	v1 := newCodecForTest(t, 1, 6, nil, nil)
+	v2 := newCodecForTest(t, 2, 6, nil, nil)
+	r := MustNewRegistry(v2, v1)
+
+	old := v1.EncodeUint64(2024)
+	u, ver, err := r.DecodeVersion(old)
+	if err != nil || u != 2024 || ver != 1 {
+		t.Fatalf("decode old: u=%d ver=%d err=%v", u, ver, err)
+	}
+	cur := r.EncodeUint64(3030)
+	u, ver, err = r.DecodeVersion(cur)
+	if err != nil || u != 3030 || ver != 2 {
+		t.Fatalf("decode cur: u=%d ver=%d err=%v", u, ver, err)
+	}
+
+	_, ver, err = r.DecodeVersion(tamperLast(v1, old))
+	if !errors.Is(err, ErrMACVerification) || ver != 1 {
+		t.Fatalf("expected MAC failure reported for version 1, got ver=%d err=%v", ver, err)
+	}
+	v3 := newCodecForTest(t, 3, 6, nil, nil)
+	if _, err := r.DecodeToUint64(v3.EncodeUint64(1)); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected unknown version, got %v", err)
+	}
+	if _, err := r.DecodeToUint64(""); !errors.Is(err, ErrInvalidLength) {
+		t.Fatalf("expected length error, got %v", err)
+	}
+	if r.Codec(1) != v1 || r.Codec(2) != v2 || r.Codec(3) != nil {
+		t.Fatalf("Codec by version mismatch")
+	}
+}
+
+func TestRegistry_NeedsReencode(t *testing.T) {
+	v1 := newCodecForTest(t, 1, 6, nil, nil)
+	v2 := newCodecForTest(t, 2, 6, nil, nil)
+	r := MustNewRegistry(v2, v1)
+
+	old := v1.EncodeUint64(55)
+	if !r.NeedsReencode(old) {
+		t.Fatalf("old token should need re-encode")
+	}
+	if r.NeedsReencode(r.EncodeUint64(55)) {
+		t.Fatalf("current token should not need re-encode")
+	}
+	if r.NeedsReencode(tamperLast(v1, old)) {
+		t.Fatalf("invalid token should not need re-encode")
+	}
+	s, err := r.Reencode(old)
+	if err != nil || s != v2.EncodeUint64(55) {
+		t.Fatalf("reencode mismatch: %q %v", s, err)
+	}
+}
+
+func TestRegistry_ConfigErrors(t *testing.T) {
+	a := newCodecForTest(t, 1, 6, nil, nil)
+	b := newCodecForTest(t, 1, 4, nil, nil)
+	if _, err := NewRegistry(a, b); !errors.Is(err, ErrBadConfig) {
+		t.Fatalf("expected duplicate version error, got %v", err)
+	}
+	if _, err := NewRegistry(nil); !errors.Is(err, ErrBadConfig) {
+		t.Fatalf("expected nil codec error, got %v", err)
+	}
+}
+
+func TestRegistry_CrockfordAnyCase(t *testing.T) {
+	v0 := MustNewCodecFromSecret(Config{Secret: []byte("phone-0"), Version: 0, MacLen: 5, Encoding: EncodingCrockford32, AllowWeakSecret: true})
+	v10 := MustNewCodecFromSecret(Config{Secret: []byte("phone-10"), Version: 10, MacLen: 5, Encoding: EncodingCrockford32, AllowWeakSecret: true})
+	r := MustNewRegistry(v10, v0)
+
+	tok := v10.EncodeUint64(77)
+	if tok[0] != 'A' {
+		t.Fatalf("version char = %q, want A", tok[0])
+	}
+	for _, s := range []string{strings.ToLower(tok), "a" + tok[1:]} {
+		if u, err := r.DecodeToUint64(s); err != nil || u != 77 {
+			t.Fatalf("decode %q: u=%d err=%v", s, u, err)
+		}
+		if u, err := r.DecodeBytes([]byte(s)); err != nil || u != 77 {
+			t.Fatalf("decode bytes %q: u=%d err=%v", s, u, err)
+		}
+	}
+	old := v0.EncodeUint64(5)
+	for _, ver := range []string{"o", "O"} {
+		if u, v, err := r.DecodeVersion(ver + old[1:]); err != nil || u != 5 || v != 0 {
+			t.Fatalf("decode %q: u=%d ver=%d err=%v", ver+old[1:], u, v, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package idcodec
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// KDF selects how the codec keys are derived from Config.Secret. The zero
+// value keeps the original sha256(secret)+label scheme so existing tokens keep
+// decoding; switch together with a new Config.Version.
+type KDF uint8
+
+const (
+	KDFLegacy KDF = iota // sha256(sha256(secret) || label), no salt
+	KDFHKDF              // HKDF-SHA256 with Config.Salt and Config.Info
+)
+
+const (
+	MinSecretLen  = 16 // bytes
+	MinSecretBits = 48 // estimated entropy, see CheckSecret
+)
+
+var (
+	ErrWeakSecret    = errors.New("idcodec: weak secret")
+	ErrSecretMissing = errors.New("idcodec: secret not set")
+)
+
+// CheckSecret rejects secrets shorter than MinSecretLen or whose estimated
+// entropy (byte frequency times length) is below MinSecretBits. It cannot
+// tell a random secret from a long passphrase, only catch the obvious ones.
+func CheckSecret(secret []byte) error {
+	if len(secret) < MinSecretLen {
+		return fmt.Errorf("%w: %d bytes, need at least %d", ErrWeakSecret, len(secret), MinSecretLen)
+	}
+	var freq [256]int
+	for _, b := range secret {
+		freq[b]++
+	}
+	var perByte float64
+	n := float64(len(secret))
+	for _, f := range freq {
+		if f > 0 {
+			p := float64(f) / n
+			perByte -= p * math.Log2(p)
+		}
+	}
+	if total := perByte * n; total < MinSecretBits {
+		return fmt.Errorf("%w: ~%.0f bits of entropy, need %d", ErrWeakSecret, total, MinSecretBits)
+	}
+	return nil
+}
+
+// DecodeSecret turns a configured secret into bytes. "base64:" and "hex:"
+// prefixes select the encoding; anything else is used verbatim.
+func DecodeSecret(s string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(s, "base64:"):
+		v := strings.TrimSpace(s[len("base64:"):])
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if b, err := enc.DecodeString(v); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("idcodec: secret is not valid base64")
+	case strings.HasPrefix(s, "hex:"):
+		b, err := hex.DecodeString(strings.TrimSpace(s[len("hex:"):]))
+		if err != nil {
+			return nil, fmt.Errorf("idcodec: secret is not valid hex: %w", err)
+		}
+		return b, nil
+	}
+	if s == "" {
+		return nil, ErrSecretMissing
+	}
+	return []byte(s), nil
+}
+
+// SecretFromEnv reads the env var name and decodes it with DecodeSecret.
+func SecretFromEnv(name string) ([]byte, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return nil, fmt.Errorf("%w: $%s is empty", ErrSecretMissing, name)
+	}
+	return DecodeSecret(v)
+}
+
+// SecretFromFile reads path, drops one trailing newline and decodes the rest
+// with DecodeSecret.
+func SecretFromFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	b = bytes.TrimSuffix(b, []byte("\r"))
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: %s is empty", ErrSecretMissing, path)
+	}
+	return DecodeSecret(string(b))
+}
+
+// keyDeriver returns the per-label key function for cfg.
+func keyDeriver(cfg Config) (func(label byte) [32]byte, error) {
+	switch cfg.KDF {
+	case KDFLegacy:
+		kMaster := sha256.Sum256(cfg.Secret)
+		return func(label byte) [32]byte {
+			h := sha256.New()
+			h.Write(kMaster[:])
+			h.Write([]byte{label})
+			var out [32]byte
+			copy(out[:], h.Sum(nil))
+			return out
+		}, nil
+	case KDFHKDF:
+		prk, err := hkdf.Extract(sha256.New, cfg.Secret, cfg.Salt)
+		if err != nil {
+			return nil, err
+		}
+		info := "idcodec/" + string(cfg.Info) + "/"
+		return func(label byte) [32]byte {
+			var out [32]byte
+			k, err := hkdf.Expand(sha256.New, prk, info+string(label), len(out))
+			if err != nil {
+				panic(err) // 32 bytes is always within HKDF-SHA256 limits
+			}
+			copy(out[:], k)
+			return out
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown kdf %d", ErrBadConfig, cfg.KDF)
+	}
+}
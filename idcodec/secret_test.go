@@ -0,0 +1,99 @@
+package idcodec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSecret(t *testing.T) {
+	for _, s := range []string{"short", "aaaaaaaaaaaaaaaaaaaaaaaa", "passwordpassword"} {
+		if err := CheckSecret([]byte(s)); !errors.Is(err, ErrWeakSecret) {
+			t.Fatalf("%q: want ErrWeakSecret, got %v", s, err)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		b := make([]byte, 32)
+		rand.Read(b)
+		if err := CheckSecret(b); err != nil {
+			t.Fatalf("random secret rejected: %v", err)
+		}
+	}
+	if err := CheckSecret([]byte("correct horse battery staple")); err != nil {
+		t.Fatalf("passphrase rejected: %v", err)
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	want := []byte{0xde, 0xad, 0xbe, 0xef}
+	for _, s := range []string{"hex:deadbeef", "base64:3q2+7w==", "base64:3q2-7w"} {
+		b, err := DecodeSecret(s)
+		if err != nil || !bytes.Equal(b, want) {
+			t.Fatalf("%q: got %x, %v", s, b, err)
+		}
+	}
+	if b, _ := DecodeSecret("plain"); string(b) != "plain" {
+		t.Fatalf("plain: got %q", b)
+	}
+	if _, err := DecodeSecret("hex:zz"); err == nil {
+		t.Fatalf("want error for bad hex")
+	}
+	if _, err := DecodeSecret(""); !errors.Is(err, ErrSecretMissing) {
+		t.Fatalf("want ErrSecretMissing, got %v", err)
+	}
+}
+
+func TestSecretLoaders(t *testing.T) {
+	t.Setenv("IDCODEC_TEST_SECRET", "hex:00ff")
+	if b, err := SecretFromEnv("IDCODEC_TEST_SECRET"); err != nil || !bytes.Equal(b, []byte{0, 0xff}) {
+		t.Fatalf("env: got %x, %v", b, err)
+	}
+	if _, err := SecretFromEnv("IDCODEC_TEST_UNSET"); !errors.Is(err, ErrSecretMissing) {
+		t.Fatalf("want ErrSecretMissing, got %v", err)
+	}
+
+	p := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(p, []byte("from file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := SecretFromFile(p); err != nil || string(b) != "from file" {
+		t.Fatalf("file: got %q, %v", b, err)
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	secret := []byte("0123456789abcdefghijklmnop")
+	legacy := MustNewCodecFromSecret(Config{Secret: secret, MacLen: 8})
+	h1 := MustNewCodecFromSecret(Config{Secret: secret, MacLen: 8, KDF: KDFHKDF, Salt: []byte("s1")})
+	h2 := MustNewCodecFromSecret(Config{Secret: secret, MacLen: 8, KDF: KDFHKDF, Salt: []byte("s2")})
+	h3 := MustNewCodecFromSecret(Config{Secret: secret, MacLen: 8, KDF: KDFHKDF, Salt: []byte("s1"), Info: []byte("orders")})
+
+	tok := h1.EncodeUint64(42)
+	if u, err := h1.DecodeToUint64(tok); err != nil || u != 42 {
+		t.Fatalf("roundtrip: %d, %v", u, err)
+	}
+	for _, c := range []*Codec{legacy, h2, h3} {
+		if c.EncodeUint64(42) == tok {
+			t.Fatalf("keys not separated by kdf/salt/info")
+		}
+		if _, err := c.DecodeToUint64(tok); err == nil {
+			t.Fatalf("token accepted under different keys")
+		}
+	}
+
+	for _, kdf := range []KDF{KDFLegacy, KDFHKDF} {
+		_, err := NewCodecFromSecret(Config{Secret: []byte("short"), MacLen: 8, KDF: kdf})
+		if !errors.Is(err, ErrWeakSecret) {
+			t.Fatalf("kdf %d: want ErrWeakSecret, got %v", kdf, err)
+		}
+		if _, err := NewCodecFromSecret(Config{Secret: []byte("x"), MacLen: 8, KDF: kdf, AllowWeakSecret: true}); err != nil {
+			t.Fatalf("kdf %d: AllowWeakSecret must skip the check: %v", kdf, err)
+		}
+	}
+	if _, err := NewCodecFromSecret(Config{Secret: secret, MacLen: 8, KDF: 9}); !errors.Is(err, ErrBadConfig) {
+		t.Fatalf("want ErrBadConfig, got %v", err)
+	}
+}
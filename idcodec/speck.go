@@ -0,0 +1,46 @@
+package idcodec
+
+import "math/bits"
+
+// Speck64/128 (Beaulieu et al., 2013): 64-bit block, 128-bit key, 27 rounds.
+// Used by PermutationSpeck as a keyed pseudo-random permutation over uint64.
+
+const speckRounds = 27
+
+type speck64 struct {
+	rk [speckRounds]uint32
+}
+
+// newSpeck64 expands key, given as the words (k0, l0, l1, l2) of the paper.
+func newSpeck64(key [4]uint32) *speck64 {
+	s := &speck64{}
+	k := key[0]
+	l := [speckRounds + 2]uint32{key[1], key[2], key[3]}
+	for i := 0; i < speckRounds; i++ {
+		s.rk[i] = k
+		if i == speckRounds-1 {
+			break
+		}
+		l[i+3] = (k + bits.RotateLeft32(l[i], -8)) ^ uint32(i)
+		k = bits.RotateLeft32(k, 3) ^ l[i+3]
+	}
+	return s
+}
+
+func (s *speck64) encrypt(v uint64) uint64 {
+	x, y := uint32(v>>32), uint32(v)
+	for _, k := range s.rk {
+		x = (bits.RotateLeft32(x, -8) + y) ^ k
+		y = bits.RotateLeft32(y, 3) ^ x
+	}
+	return uint64(x)<<32 | uint64(y)
+}
+
+func (s *speck64) decrypt(v uint64) uint64 {
+	x, y := uint32(v>>32), uint32(v)
+	for i := speckRounds - 1; i >= 0; i-- {
+		y = bits.RotateLeft32(y^x, -3)
+		x = bits.RotateLeft32((x^s.rk[i])-y, 8)
+	}
+	return uint64(x)<<32 | uint64(y)
+}
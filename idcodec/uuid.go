@@ -0,0 +1,124 @@
+package idcodec
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// 128-bit tokens: version char + full-width body (22 base62 digits) + MAC.
+// The body is a 4-round Feistel network over the two 64-bit halves whose
+// round function is the codec's 64-bit permutation, so PermutationSpeck
+// strengthens both.
+
+const uuidMarker = 'U' // separates 128-bit MAC inputs from 64-bit ones
+
+func (c *Codec) feistelRound(i int, x uint64) uint64 {
+	return c.permutation(x ^ c.uk[i])
+}
+
+func (c *Codec) permute128(hi, lo uint64) (uint64, uint64) {
+	for i := range c.uk {
+		hi, lo = lo, hi^c.feistelRound(i, lo)
+	}
+	return hi, lo
+}
+
+func (c *Codec) inversePermute128(hi, lo uint64) (uint64, uint64) {
+	for i := len(c.uk) - 1; i >= 0; i-- {
+		hi, lo = lo^c.feistelRound(i, hi), hi
+	}
+	return hi, lo
+}
+
+func (c *Codec) appendDigits128(dst []byte, hi, lo uint64) []byte {
+	var buf [128]byte
+	n := c.uuidLen
+	for i := n - 1; i >= 0; i-- {
+		var r uint64
+		hi, r = hi/c.radix, hi%c.radix
+		lo, r = bits.Div64(r, lo, c.radix)
+		buf[i] = c.alphabet[r]
+	}
+	return append(dst, buf[:n]...)
+}
+
+func (c *Codec) decodeDigits128(s string) (uint64, uint64, error) {
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		v := c.rev[s[i]]
+		if v < 0 {
+			return 0, 0, ErrInvalidBase62Char
+		}
+		// (hi,lo) = (hi,lo)*radix + v
+		ovf, h := bits.Mul64(hi, c.radix)
+		if ovf != 0 {
+			return 0, 0, ErrOverflow
+		}
+		carry, l := bits.Mul64(lo, c.radix)
+		l, c0 := bits.Add64(l, uint64(v), 0)
+		h, c1 := bits.Add64(h, carry, c0)
+		if c1 != 0 {
+			return 0, 0, ErrOverflow
+		}
+		hi, lo = h, l
+	}
+	return hi, lo, nil
+}
+
+func (c *Codec) uuidMAC(hi, lo uint64, kind *byte) string {
+	var b [17]byte
+	b[0] = uuidMarker
+	binary.BigEndian.PutUint64(b[1:9], hi)
+	binary.BigEndian.PutUint64(b[9:], lo)
+	return c.macChars(b[:], kind)
+}
+
+func (c *Codec) EncodeUUID(u [16]byte) string {
+	return c.EncodeUUIDWithKind(u, c.kind)
+}
+
+func (c *Codec) EncodeUUIDWithKind(u [16]byte, kind *byte) string {
+	hi, lo := c.permute128(binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:]))
+	b := make([]byte, 0, 1+c.uuidLen+c.macLen)
+	b = append(b, c.versionChar())
+	b = c.appendDigits128(b, hi, lo)
+	return c.withCheck(string(b) + c.uuidMAC(hi, lo, kind))
+}
+
+func (c *Codec) DecodeUUID(s string) ([16]byte, error) {
+	return c.DecodeUUIDWithKind(s, c.kind)
+}
+
+func (c *Codec) DecodeUUIDWithKind(s string, kind *byte) ([16]byte, error) {
+	var out [16]byte
+	if c.check && len(s) != 1+c.uuidLen+c.macLen+1 {
+		return out, ErrInvalidLength
+	}
+	s, err := c.stripCheck(s)
+	if err != nil {
+		return out, err
+	}
+	if len(s) != 1+c.uuidLen+c.macLen {
+		return out, ErrInvalidLength
+	}
+	if c.rev[s[0]] != int8(c.version) {
+		return out, ErrVersionMismatch
+	}
+	hi, lo, err := c.decodeDigits128(s[1 : 1+c.uuidLen])
+	if err != nil {
+		return out, err
+	}
+	exp := c.uuidMAC(hi, lo, kind)
+	if !macEqual(c, s, 1+c.uuidLen, exp) {
+		return out, ErrMACVerification
+	}
+	hi, lo = c.inversePermute128(hi, lo)
+	binary.BigEndian.PutUint64(out[:8], hi)
+	binary.BigEndian.PutUint64(out[8:], lo)
+	return out, nil
+}
+
+func (c *Codec) ValidateUUID(s string) error {
+	_, err := c.DecodeUUID(s)
+	return err
+}
@@ -0,0 +1,52 @@
+package idcodec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUUID_RoundTrip(t *testing.T) {
+	for _, perm := range []Permutation{PermutationLegacy, PermutationSpeck} {
+		c := MustNewCodecFromSecret(Config{Secret: []byte("uuid"), Version: 3, MacLen: 6, Permutation: perm, AllowWeakSecret: true})
+		cases := [][16]byte{
+			{},
+			{15: 1},
+			{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8},
+		}
+		for _, u := range cases {
+			s := c.EncodeUUID(u)
+			if len(s) != 1+22+6 || s[0] != c.alphabet[3] {
+				t.Fatalf("token shape: %q", s)
+			}
+			got, err := c.DecodeUUID(s)
+			if err != nil || got != u {
+				t.Fatalf("round-trip %x: got %x err %v", u, got, err)
+			}
+		}
+	}
+}
+
+func TestUUID_Errors(t *testing.T) {
+	c := newCodecForTest(t, 0, 6, []byte("dom"), nil)
+	u := [16]byte{1, 2, 3}
+	s := c.EncodeUUID(u)
+	if _, err := c.DecodeUUID(tamperLast(c, s)); !errors.Is(err, ErrMACVerification) {
+		t.Fatalf("expected MAC failure, got %v", err)
+	}
+	if _, err := c.DecodeToUint64(s); !errors.Is(err, ErrInvalidLength) {
+		t.Fatalf("uuid token must not decode as uint64: %v", err)
+	}
+	if _, err := c.DecodeUUID(c.EncodeUint64(1)); !errors.Is(err, ErrInvalidLength) {
+		t.Fatalf("uint64 token must not decode as uuid: %v", err)
+	}
+	over := s[:1] + strings.Repeat("z", 22) + s[23:]
+	if _, err := c.DecodeUUID(over); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected overflow, got %v", err)
+	}
+	k := byte('K')
+	if _, err := c.DecodeUUIDWithKind(s, &k); !errors.Is(err, ErrMACVerification) {
+		t.Fatalf("kind should be bound to uuid tokens: %v", err)
+	}
+}
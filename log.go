@@ -0,0 +1,166 @@
+package gx
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedID is logged in place of an ID when no codec is configured, so raw
+// database ids never reach the logs.
+const RedactedID = "ID(redacted)"
+
+// RedactedUUID is RedactedID for OpaqueUUID.
+const RedactedUUID = "UUID(redacted)"
+
+// Redacted returns the encoded ID, or RedactedID when no codec is set. Unlike
+// String it never prints the raw number.
+func (i ID) Redacted() string {
+	c := defaultCodec.Load()
+	if c == nil {
+		return RedactedID
+	}
+	return c.EncodeUint64(uint64(i))
+}
+
+// LogValue makes slog print the redacted form.
+func (i ID) LogValue() slog.Value {
+	return slog.StringValue(i.Redacted())
+}
+
+// MarshalLogObject logs the ID as {"id": "<token>"} with zap.Object.
+func (i ID) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("id", i.Redacted())
+	return nil
+}
+
+func (n NullID) Redacted() string {
+	if !n.Valid {
+		return "null"
+	}
+	return n.ID.Redacted()
+}
+
+func (n NullID) LogValue() slog.Value {
+	return slog.StringValue(n.Redacted())
+}
+
+func (i TypedID[K]) Redacted() string {
+	s, err := i.encode()
+	if err != nil {
+		return RedactedID
+	}
+	return s
+}
+
+func (i TypedID[K]) LogValue() slog.Value {
+	return slog.StringValue(i.Redacted())
+}
+
+func (i ScopedID[S]) Redacted() string {
+	s, err := i.encode()
+	if err != nil {
+		return RedactedID
+	}
+	return s
+}
+
+func (i ScopedID[S]) LogValue() slog.Value {
+	return slog.StringValue(i.Redacted())
+}
+
+func (o OpaqueUUID) Redacted() string {
+	c, err := getUUIDCodec()
+	if err != nil {
+		return RedactedUUID
+	}
+	return c.EncodeUUID(o)
+}
+
+func (o OpaqueUUID) LogValue() slog.Value {
+	return slog.StringValue(o.Redacted())
+}
+
+func (o OpaqueUUID) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("id", o.Redacted())
+	return nil
+}
+
+// ZapID is a zap field holding the encoded (or redacted) id.
+func ZapID(key string, id interface{ Redacted() string }) zap.Field {
+	return zap.String(key, id.Redacted())
+}
+
+// ZapIDs is ZapID for a slice of IDs.
+func ZapIDs(key string, ids []ID) zap.Field {
+	return zap.Array(key, zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		for _, id := range ids {
+			enc.AppendString(id.Redacted())
+		}
+		return nil
+	}))
+}
+
+// ---------- Secret ----------
+
+const redacted = "[REDACTED]"
+
+// Secret holds a value that must not be printed: fmt, JSON, text, slog and
+// zap all see "[REDACTED]". Use Reveal to get the value. Secrets can still be
+// loaded from JSON or text config.
+type Secret[T any] struct {
+	v T
+}
+
+func NewSecret[T any](v T) Secret[T] {
+	return Secret[T]{v: v}
+}
+
+func (s Secret[T]) Reveal() T {
+	return s.v
+}
+
+func (s Secret[T]) String() string   { return redacted }
+func (s Secret[T]) GoString() string { return redacted }
+
+// Format covers every verb, including %v on a struct holding s and %#v.
+func (s Secret[T]) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(redacted))
+}
+
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s *Secret[T]) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &s.v)
+}
+
+func (s Secret[T]) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// UnmarshalText accepts the value itself when T is a string or []byte.
+func (s *Secret[T]) UnmarshalText(b []byte) error {
+	switch p := any(&s.v).(type) {
+	case *string:
+		*p = string(b)
+	case *[]byte:
+		*p = append([]byte(nil), b...)
+	default:
+		return fmt.Errorf("gx: Secret[%T] cannot be read from text", s.v)
+	}
+	return nil
+}
+
+func (s Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+func (s Secret[T]) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("secret", redacted)
+	return nil
+}
@@ -0,0 +1,114 @@
+package gx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestIDRedacted(t *testing.T) {
+	defaultCodec.Store(nil)
+	id := ID(987654321)
+	if id.Redacted() != RedactedID {
+		t.Fatalf("no codec: %q", id.Redacted())
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("x", "id", id)
+	if strings.Contains(buf.String(), "987654321") {
+		t.Fatalf("slog leaked raw id: %s", buf.String())
+	}
+
+	SetDefaultCodec(codecForGX(t))
+	if id.Redacted() != id.String() {
+		t.Fatalf("with codec: %q", id.Redacted())
+	}
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Info("x", ZapID("id", id), ZapID("typed", TypedID[userKind](3)), zap.Object("obj", id), ZapIDs("ids", []ID{1, 2}))
+	f := logs.All()[0].ContextMap()
+	if f["id"] != id.String() || f["typed"] != TypedID[userKind](3).String() {
+		t.Fatalf("zap fields: %v", f)
+	}
+	if obj, _ := f["obj"].(map[string]any); obj["id"] != id.String() {
+		t.Fatalf("zap object: %v", f["obj"])
+	}
+	if ids, _ := f["ids"].([]any); len(ids) != 2 || ids[0] != ID(1).String() {
+		t.Fatalf("zap ids: %v", f["ids"])
+	}
+	if (NullID{}).Redacted() != "null" {
+		t.Fatalf("null redacted")
+	}
+}
+
+func TestIDStringNoCodec(t *testing.T) {
+	defaultCodec.Store(nil)
+	id := ID(987654321)
+	u := OpaqueUUID(uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	outputs := []string{
+		fmt.Sprint(id), fmt.Sprintf("%v %s", id, TypedID[userKind](987654321)), fmt.Sprint(u),
+	}
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Info("x", zap.Stringer("id", id), zap.Stringer("u", u), zap.Object("o", u))
+	outputs = append(outputs, fmt.Sprint(logs.All()[0].ContextMap()))
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("x", "u", u)
+	outputs = append(outputs, buf.String())
+
+	for _, out := range outputs {
+		if strings.Contains(out, "987654321") || strings.Contains(out, "6ba7b810") {
+			t.Fatalf("raw value leaked: %s", out)
+		}
+	}
+	if fmt.Sprint(id) != RedactedID || u.String() != RedactedUUID {
+		t.Fatalf("got %q %q", fmt.Sprint(id), u.String())
+	}
+}
+
+func TestSecret(t *testing.T) {
+	type cfg struct {
+		User string
+		Pass Secret[string]
+	}
+	c := cfg{User: "u", Pass: NewSecret("hunter2")}
+	outputs := []string{
+		fmt.Sprint(c.Pass), fmt.Sprintf("%v %+v %#v %s %q", c, c, c, c.Pass, c.Pass),
+	}
+	b, _ := json.Marshal(c)
+	outputs = append(outputs, string(b))
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("x", "pass", c.Pass)
+	outputs = append(outputs, buf.String())
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Info("x", zap.Any("pass", c.Pass), zap.Stringer("s", c.Pass))
+	outputs = append(outputs, fmt.Sprint(logs.All()[0].ContextMap()))
+
+	for _, out := range outputs {
+		if strings.Contains(out, "hunter2") {
+			t.Fatalf("secret leaked: %s", out)
+		}
+	}
+	if c.Pass.Reveal() != "hunter2" {
+		t.Fatalf("reveal: %q", c.Pass.Reveal())
+	}
+
+	var in cfg
+	if err := json.Unmarshal([]byte(`{"User":"u","Pass":"p@ss"}`), &in); err != nil || in.Pass.Reveal() != "p@ss" {
+		t.Fatalf("unmarshal: %v %v", in.Pass.Reveal(), err)
+	}
+	var s Secret[[]byte]
+	if err := s.UnmarshalText([]byte("k")); err != nil || string(s.Reveal()) != "k" {
+		t.Fatalf("text: %v", err)
+	}
+	var n Secret[int]
+	if err := n.UnmarshalText([]byte("1")); err == nil {
+		t.Fatalf("want error for non-string text")
+	}
+}
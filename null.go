@@ -0,0 +1,118 @@
+package gx
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// Null is an optional T with three JSON states, as needed for PATCH bodies:
+// absent (Set false), null (Set true, Valid false) and a value (both true).
+// Tag fields `json:",omitzero"` so absent values are left out on output.
+type Null[T any] struct {
+	V     T
+	Valid bool // a value is present
+	Set   bool // the field was present in the input, possibly as null
+}
+
+// Some returns a present value.
+func Some[T any](v T) Null[T] {
+	return Null[T]{V: v, Valid: true, Set: true}
+}
+
+// NullOf returns an explicit null, as opposed to the absent zero value.
+func NullOf[T any]() Null[T] {
+	return Null[T]{Set: true}
+}
+
+// NullFromPtr returns Some(*p), or an explicit null for a nil p.
+func NullFromPtr[T any](p *T) Null[T] {
+	if p == nil {
+		return NullOf[T]()
+	}
+	return Some(*p)
+}
+
+// Ptr returns a pointer to a copy of the value, or nil.
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.V
+	return &v
+}
+
+func (n Null[T]) Get() (T, bool) {
+	return n.V, n.Valid
+}
+
+// IsNull reports an explicit null.
+func (n Null[T]) IsNull() bool {
+	return n.Set && !n.Valid
+}
+
+// IsZero reports an absent value; encoding/json's omitzero uses it.
+func (n Null[T]) IsZero() bool {
+	return !n.Set && !n.Valid
+}
+
+func (n Null[T]) OrElse(def T) T {
+	if n.Valid {
+		return n.V
+	}
+	return def
+}
+
+func (n Null[T]) OrElseGet(f func() T) T {
+	if n.Valid {
+		return n.V
+	}
+	return f()
+}
+
+// NullMap applies f to a present value, keeping absent and null as they are.
+func NullMap[T, U any](n Null[T], f func(T) U) Null[U] {
+	if !n.Valid {
+		return Null[U]{Set: n.Set}
+	}
+	return Null[U]{V: f(n.V), Valid: true, Set: true}
+}
+
+func (n Null[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.V)
+}
+
+func (n *Null[T]) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		var zero T
+		*n = Null[T]{V: zero, Set: true}
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*n = Some(v)
+	return nil
+}
+
+// Scan converts src like database/sql does for a *T destination, so T may be
+// any type sql.Rows.Scan accepts, including sql.Scanner implementations.
+func (n *Null[T]) Scan(src any) error {
+	var s sql.Null[T]
+	if err := s.Scan(src); err != nil {
+		return err
+	}
+	*n = Null[T]{V: s.V, Valid: s.Valid, Set: true}
+	return nil
+}
+
+// Value returns nil for absent and null, otherwise V as a driver.Value (via
+// driver.Valuer if T implements it).
+func (n Null[T]) Value() (driver.Value, error) {
+	return sql.Null[T]{V: n.V, Valid: n.Valid}.Value()
+}
@@ -0,0 +1,93 @@
+package gx
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNullJSONTriState(t *testing.T) {
+	type patch struct {
+		Name Null[string] `json:"name,omitzero"`
+		Age  Null[int]    `json:"age,omitzero"`
+		Nick Null[string] `json:"nick,omitzero"`
+	}
+	var p patch
+	if err := json.Unmarshal([]byte(`{"name":"a","age":null}`), &p); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if v, ok := p.Name.Get(); !ok || v != "a" {
+		t.Fatalf("name: %+v", p.Name)
+	}
+	if !p.Age.IsNull() || p.Age.Valid {
+		t.Fatalf("age must be explicit null: %+v", p.Age)
+	}
+	if !p.Nick.IsZero() || p.Nick.Set {
+		t.Fatalf("nick must be absent: %+v", p.Nick)
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil || string(b) != `{"name":"a","age":null}` {
+		t.Fatalf("marshal: %s %v", b, err)
+	}
+	b, _ = json.Marshal(patch{Name: Null[string]{V: "x", Valid: true}})
+	if string(b) != `{"name":"x"}` {
+		t.Fatalf("Valid without Set must still be emitted: %s", b)
+	}
+}
+
+func TestNullHelpers(t *testing.T) {
+	n := Some(20)
+	if s := NullMap(n, strconv.Itoa); s.OrElse("") != "20" {
+		t.Fatalf("map: %+v", s)
+	}
+	if m := NullMap(NullOf[int](), strconv.Itoa); !m.IsNull() {
+		t.Fatalf("map must keep null: %+v", m)
+	}
+	if m := NullMap(Null[int]{}, strconv.Itoa); !m.IsZero() {
+		t.Fatalf("map must keep absent: %+v", m)
+	}
+	if v := NullOf[int]().OrElseGet(func() int { return 7 }); v != 7 {
+		t.Fatalf("OrElseGet: %d", v)
+	}
+	if p := NullFromPtr[int](nil); !p.IsNull() || p.Ptr() != nil {
+		t.Fatalf("from nil: %+v", p)
+	}
+	if p := NullFromPtr(Ptr(3)).Ptr(); p == nil || *p != 3 {
+		t.Fatalf("ptr roundtrip: %v", p)
+	}
+}
+
+func TestNullSQL(t *testing.T) {
+	var s Null[string]
+	if err := s.Scan([]byte("hi")); err != nil || s.OrElse("") != "hi" {
+		t.Fatalf("scan string: %+v %v", s, err)
+	}
+	if err := s.Scan(nil); err != nil || !s.IsNull() {
+		t.Fatalf("scan nil: %+v %v", s, err)
+	}
+	var i Null[int32]
+	if err := i.Scan(int64(5)); err != nil || i.V != 5 {
+		t.Fatalf("scan int32: %+v %v", i, err)
+	}
+	var tm Null[time.Time]
+	now := time.Now()
+	if err := tm.Scan(now); err != nil || !tm.V.Equal(now) {
+		t.Fatalf("scan time: %+v %v", tm, err)
+	}
+	var id Null[ID]
+	if err := id.Scan(int64(9)); err != nil || id.V != 9 {
+		t.Fatalf("scan ID: %+v %v", id, err)
+	}
+
+	if v, err := (Null[string]{}).Value(); err != nil || v != nil {
+		t.Fatalf("absent value: %v %v", v, err)
+	}
+	if v, err := Some(int32(4)).Value(); err != nil || v != int64(4) {
+		t.Fatalf("int32 value: %#v %v", v, err)
+	}
+	if v, err := Some(ID(6)).Value(); err != nil || v != int64(6) {
+		t.Fatalf("ID value: %#v %v", v, err)
+	}
+}
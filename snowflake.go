@@ -0,0 +1,194 @@
+package gx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snowflake mints k-sortable IDs laid out as | time | node | sequence |, most
+// significant first. The layout fits in 63 bits so IDs stay valid BIGINTs.
+type Snowflake struct {
+	mu       sync.Mutex
+	epoch    time.Time
+	unit     time.Duration
+	nodeBits uint8
+	seqBits  uint8
+	maxTime  int64
+	maxSeq   int64
+	node     int64
+	backoff  time.Duration
+	now      func() time.Time
+
+	last int64 // tick of the last ID
+	seq  int64
+}
+
+type SnowflakeOpts struct {
+	Epoch    time.Time     // default 2020-01-01 UTC
+	Unit     time.Duration // tick length, default 1ms
+	TimeBits uint8         // default 41 (~69 years of ms)
+	NodeBits uint8         // default 10
+	SeqBits  uint8         // default 12
+	NodeID   int64
+	NodeEnv  string // optional env var overriding NodeID, e.g. "GX_NODE_ID"
+
+	// MaxClockBackwards is how far the clock may step back before Next fails
+	// with ErrClockBackwards; within it IDs continue from the last tick.
+	// Default 1s.
+	MaxClockBackwards time.Duration
+	Now               func() time.Time // optional, for tests
+}
+
+var (
+	ErrClockBackwards     = errors.New("gx.Snowflake: clock moved backwards")
+	ErrSnowflakeExhausted = errors.New("gx.Snowflake: time bits exhausted")
+	ErrSnowflakeStalled   = errors.New("gx.Snowflake: sequence used up and the clock does not advance")
+)
+
+// SnowflakeParts is an ID split into its fields.
+type SnowflakeParts struct {
+	Time time.Time
+	Node int64
+	Seq  int64
+}
+
+func NewSnowflake(opts SnowflakeOpts) (*Snowflake, error) {
+	if opts.Epoch.IsZero() {
+		opts.Epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if opts.Unit <= 0 {
+		opts.Unit = time.Millisecond
+	}
+	if opts.TimeBits == 0 && opts.NodeBits == 0 && opts.SeqBits == 0 {
+		opts.TimeBits, opts.NodeBits, opts.SeqBits = 41, 10, 12
+	}
+	if opts.TimeBits == 0 || opts.SeqBits == 0 {
+		return nil, errors.New("gx.Snowflake: TimeBits and SeqBits must be > 0")
+	}
+	if int(opts.TimeBits)+int(opts.NodeBits)+int(opts.SeqBits) > 63 {
+		return nil, errors.New("gx.Snowflake: TimeBits+NodeBits+SeqBits must be <= 63")
+	}
+	if opts.MaxClockBackwards <= 0 {
+		opts.MaxClockBackwards = time.Second
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.NodeEnv != "" {
+		if v, ok := os.LookupEnv(opts.NodeEnv); ok {
+			n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("gx.Snowflake: $%s: %w", opts.NodeEnv, err)
+			}
+			opts.NodeID = n
+		}
+	}
+	if maxNode := int64(1)<<opts.NodeBits - 1; opts.NodeID < 0 || opts.NodeID > maxNode {
+		return nil, fmt.Errorf("gx.Snowflake: NodeID %d out of range [0,%d]", opts.NodeID, maxNode)
+	}
+	return &Snowflake{
+		epoch:    opts.Epoch,
+		unit:     opts.Unit,
+		nodeBits: opts.NodeBits,
+		seqBits:  opts.SeqBits,
+		maxTime:  int64(1)<<opts.TimeBits - 1,
+		maxSeq:   int64(1)<<opts.SeqBits - 1,
+		node:     opts.NodeID,
+		backoff:  opts.MaxClockBackwards,
+		now:      opts.Now,
+		last:     -1,
+	}, nil
+}
+
+func MustNewSnowflake(opts SnowflakeOpts) *Snowflake {
+	s, err := NewSnowflake(opts)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Snowflake) tick() int64 {
+	return int64(s.now().Sub(s.epoch) / s.unit)
+}
+
+// Next returns a new ID, larger than every ID this generator returned before.
+// When the sequence of a tick is used up it waits, without holding the lock,
+// for the next tick; if the clock has not moved past the last tick after
+// MaxClockBackwards plus one tick, it fails with ErrSnowflakeStalled. A failed
+// call leaves the generator unchanged.
+func (s *Snowflake) Next() (ID, error) {
+	var waited time.Duration
+	for {
+		id, full, err := s.next()
+		if !full {
+			return id, err
+		}
+		if waited > s.backoff+s.unit {
+			return 0, ErrSnowflakeStalled
+		}
+		pause := max(s.unit/4, time.Microsecond)
+		time.Sleep(pause)
+		waited += pause
+	}
+}
+
+// next mints an ID for the current tick, or reports full when the tick's
+// sequence is used up.
+func (s *Snowflake) next() (id ID, full bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tick()
+	if t < s.last {
+		if time.Duration(s.last-t)*s.unit > s.backoff {
+			return 0, false, fmt.Errorf("%w by %v", ErrClockBackwards, time.Duration(s.last-t)*s.unit)
+		}
+		t = s.last // small step back: keep counting on the last tick
+	}
+	var seq int64
+	if t == s.last {
+		if seq = s.seq + 1; seq > s.maxSeq {
+			return 0, true, nil
+		}
+	}
+	if t > s.maxTime {
+		return 0, false, ErrSnowflakeExhausted
+	}
+	if t < 0 {
+		return 0, false, fmt.Errorf("gx.Snowflake: clock is before epoch %v", s.epoch)
+	}
+	s.last, s.seq = t, seq
+	return ID(uint64(t)<<(s.nodeBits+s.seqBits) | uint64(s.node)<<s.seqBits | uint64(seq)), false, nil
+}
+
+func (s *Snowflake) MustNext() ID {
+	id, err := s.Next()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// Node returns the node ID embedded in every ID of s.
+func (s *Snowflake) Node() int64 {
+	return s.node
+}
+
+// Decompose splits id using the layout of s.
+func (s *Snowflake) Decompose(id ID) SnowflakeParts {
+	u := uint64(id)
+	seq := int64(u & uint64(s.maxSeq))
+	node := int64(u>>s.seqBits) & (int64(1)<<s.nodeBits - 1)
+	t := int64(u >> (s.nodeBits + s.seqBits))
+	return SnowflakeParts{
+		Time: s.epoch.Add(time.Duration(t) * s.unit),
+		Node: node,
+		Seq:  seq,
+	}
+}
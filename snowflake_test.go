@@ -0,0 +1,123 @@
+package gx
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func TestSnowflakeLayoutAndDecompose(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s := MustNewSnowflake(SnowflakeOpts{NodeID: 7, Now: clk.Now})
+
+	a, b := s.MustNext(), s.MustNext()
+	if b <= a {
+		t.Fatalf("not increasing: %d %d", a, b)
+	}
+	p := s.Decompose(b)
+	if !p.Time.Equal(clk.t) || p.Node != 7 || p.Seq != 1 {
+		t.Fatalf("decompose: %+v", p)
+	}
+	clk.t = clk.t.Add(time.Millisecond)
+	if p := s.Decompose(s.MustNext()); p.Seq != 0 || !p.Time.Equal(clk.t) {
+		t.Fatalf("new tick must reset seq: %+v", p)
+	}
+}
+
+func TestSnowflakeClockBackwards(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s := MustNewSnowflake(SnowflakeOpts{Now: clk.Now, MaxClockBackwards: 5 * time.Millisecond})
+	a := s.MustNext()
+
+	clk.t = clk.t.Add(-3 * time.Millisecond)
+	b, err := s.Next()
+	if err != nil || b <= a {
+		t.Fatalf("small regression: %d %v", b, err)
+	}
+	clk.t = clk.t.Add(-time.Second)
+	if _, err := s.Next(); !errors.Is(err, ErrClockBackwards) {
+		t.Fatalf("want ErrClockBackwards, got %v", err)
+	}
+}
+
+func TestSnowflakeSequenceRollover(t *testing.T) {
+	s := MustNewSnowflake(SnowflakeOpts{TimeBits: 41, NodeBits: 0, SeqBits: 2})
+	var prev ID
+	for i := 0; i < 20; i++ {
+		id := s.MustNext()
+		if id <= prev {
+			t.Fatalf("not increasing at %d: %d <= %d", i, id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestSnowflakeFrozenClockOverflow(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	s := MustNewSnowflake(SnowflakeOpts{NodeBits: 0, TimeBits: 41, SeqBits: 2, Now: clk.Now, MaxClockBackwards: 10 * time.Millisecond})
+	for i := 0; i < 4; i++ {
+		s.MustNext()
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Next()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrSnowflakeStalled) {
+			t.Fatalf("want ErrSnowflakeStalled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Next blocked on a frozen clock")
+	}
+
+	// The failed call must not have used up anything: the next tick starts
+	// at seq 0.
+	clk.t = clk.t.Add(time.Millisecond)
+	if p := s.Decompose(s.MustNext()); p.Seq != 0 || !p.Time.Equal(clk.t) {
+		t.Fatalf("after stall: %+v", p)
+	}
+}
+
+func TestSnowflakeErrorsKeepState(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 5, 0, time.UTC)}
+	s := MustNewSnowflake(SnowflakeOpts{TimeBits: 3, SeqBits: 4, Unit: time.Second, Now: clk.Now})
+	s.MustNext()
+
+	clk.t = clk.t.Add(3 * time.Second) // tick 8 does not fit in 3 bits
+	if _, err := s.Next(); !errors.Is(err, ErrSnowflakeExhausted) {
+		t.Fatalf("want ErrSnowflakeExhausted, got %v", err)
+	}
+	clk.t = clk.t.Add(-3 * time.Second)
+	if p := s.Decompose(s.MustNext()); p.Seq != 1 {
+		t.Fatalf("seq after failed call = %d, want 1", p.Seq)
+	}
+}
+
+func TestSnowflakeOpts(t *testing.T) {
+	t.Setenv("GX_TEST_NODE", "12")
+	s, err := NewSnowflake(SnowflakeOpts{NodeID: 1, NodeEnv: "GX_TEST_NODE"})
+	if err != nil || s.Node() != 12 {
+		t.Fatalf("node from env: %v %v", s, err)
+	}
+	for _, o := range []SnowflakeOpts{
+		{NodeID: 1024},
+		{TimeBits: 50, NodeBits: 10, SeqBits: 10},
+		{NodeEnv: "GX_TEST_NODE", NodeBits: 2, TimeBits: 41, SeqBits: 12},
+	} {
+		if _, err := NewSnowflake(o); err == nil {
+			t.Fatalf("%+v: want error", o)
+		}
+	}
+
+	clk := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 8, 0, time.UTC)}
+	s = MustNewSnowflake(SnowflakeOpts{TimeBits: 3, SeqBits: 4, Unit: time.Second, Now: clk.Now})
+	if _, err := s.Next(); !errors.Is(err, ErrSnowflakeExhausted) {
+		t.Fatalf("want ErrSnowflakeExhausted, got %v", err)
+	}
+}
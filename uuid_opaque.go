@@ -0,0 +1,120 @@
+package gx
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// OpaqueUUID is a uuid.UUID that is exposed as an idcodec token in JSON and
+// text, and stored as a plain UUID in the database.
+type OpaqueUUID uuid.UUID
+
+func getUUIDCodec() (UUIDCodec, error) {
+	c, err := getCodec()
+	if err != nil {
+		return nil, err
+	}
+	uc, ok := c.(UUIDCodec)
+	if !ok {
+		return nil, fmt.Errorf("gx: codec %T does not support UUIDs", c)
+	}
+	return uc, nil
+}
+
+func NewOpaqueUUID(u uuid.UUID) *OpaqueUUID { x := OpaqueUUID(u); return &x }
+func (o OpaqueUUID) UUID() uuid.UUID        { return uuid.UUID(o) }
+
+func (o OpaqueUUID) MarshalJSON() ([]byte, error) {
+	c, err := getUUIDCodec()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(c.EncodeUUID(o))
+}
+
+// UnmarshalJSON accepts null, "" (both yielding uuid.Nil) or an encoded
+// token; raw UUID strings are rejected so clients cannot bypass the codec.
+func (o *OpaqueUUID) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*o = OpaqueUUID{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New("gx: uuid must be an encoded string")
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*o = OpaqueUUID{}
+		return nil
+	}
+	c, err := getUUIDCodec()
+	if err != nil {
+		return err
+	}
+	u, err := c.DecodeUUID(s)
+	if err != nil {
+		return fmt.Errorf("gx: invalid encoded UUID: %w", err)
+	}
+	*o = OpaqueUUID(u)
+	return nil
+}
+
+var _ encoding.TextMarshaler = (*OpaqueUUID)(nil)
+var _ encoding.TextUnmarshaler = (*OpaqueUUID)(nil)
+
+func (o OpaqueUUID) MarshalText() ([]byte, error) {
+	c, err := getUUIDCodec()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(c.EncodeUUID(o)), nil
+}
+
+func (o *OpaqueUUID) UnmarshalText(b []byte) error {
+	c, err := getUUIDCodec()
+	if err != nil {
+		return err
+	}
+	u, err := c.DecodeUUID(strings.TrimSpace(string(b)))
+	if err != nil {
+		return err
+	}
+	*o = OpaqueUUID(u)
+	return nil
+}
+
+func (o OpaqueUUID) Value() (driver.Value, error) {
+	return uuid.UUID(o).Value()
+}
+
+func (o *OpaqueUUID) Scan(src any) error {
+	var u uuid.UUID
+	if err := u.Scan(src); err != nil {
+		return fmt.Errorf("gx: scan uuid: %w", err)
+	}
+	*o = OpaqueUUID(u)
+	return nil
+}
+
+func (o OpaqueUUID) String() string {
+	return o.Redacted()
+}
+
+func ParseOpaqueUUIDString(s string) (*OpaqueUUID, error) {
+	c, err := getUUIDCodec()
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.DecodeUUID(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return NewOpaqueUUID(u), nil
+}
@@ -0,0 +1,48 @@
+package gx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestOpaqueUUID_JSONTextSQL(t *testing.T) {
+	defaultCodec.Store(nil)
+	SetDefaultCodec(codecForGX(t))
+	raw := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	type Doc struct {
+		ID OpaqueUUID `json:"id"`
+	}
+	b, err := json.Marshal(Doc{ID: OpaqueUUID(raw)})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("unmarshal raw: %v", err)
+	}
+	if m["id"] == raw.String() || m["id"] == "" {
+		t.Fatalf("uuid not encoded: %q", m["id"])
+	}
+	var d Doc
+	if err := json.Unmarshal(b, &d); err != nil || d.ID.UUID() != raw {
+		t.Fatalf("round-trip failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"`+raw.String()+`"}`), &d); err == nil {
+		t.Fatalf("raw uuid should be rejected")
+	}
+
+	p, err := ParseOpaqueUUIDString(OpaqueUUID(raw).String())
+	if err != nil || p.UUID() != raw {
+		t.Fatalf("ParseOpaqueUUIDString failed: %v", err)
+	}
+	v, err := OpaqueUUID(raw).Value()
+	if err != nil || v != raw.String() {
+		t.Fatalf("Value mismatch: %v %v", v, err)
+	}
+	var s OpaqueUUID
+	if err := s.Scan(raw.String()); err != nil || s.UUID() != raw {
+		t.Fatalf("Scan failed: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package idparam
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bronystylecrazy/gx"
+	"github.com/bronystylecrazy/gx/idcodec"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Source string
+
+const (
+	SourceParam  Source = "param"
+	SourceQuery  Source = "query"
+	SourceHeader Source = "header"
+)
+
+// Error is a failed ID lookup. Malformed input is a 400; a token that parses
+// but fails its MAC or kind check is a 404, so guessing reveals nothing.
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"` // missing_id, invalid_id, not_found or id_codec_error
+	Source  Source `json:"source"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("idparam: %s %q: %s", e.Source, e.Name, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Spec describes one ID to bind: where to read it and how to decode it.
+type Spec struct {
+	source   Source
+	name     string
+	local    string
+	optional bool
+	decode   func(c *fiber.Ctx, s string) (any, error)
+}
+
+func decodeID(c *fiber.Ctx, s string) (any, error) {
+	id, err := gx.ParseIDStringContext(c.UserContext(), s)
+	if err != nil {
+		return nil, err
+	}
+	return *id, nil
+}
+
+func decodeTyped[K gx.IDKind](c *fiber.Ctx, s string) (any, error) {
+	id, err := gx.ParseTypedIDStringContext[K](c.UserContext(), s)
+	if err != nil {
+		return nil, err
+	}
+	return *id, nil
+}
+
+// Param binds the path param name as a gx.ID.
+func Param(name string) Spec { return Spec{source: SourceParam, name: name, decode: decodeID} }
+
+// Query binds the query arg name as a gx.ID.
+func Query(name string) Spec { return Spec{source: SourceQuery, name: name, decode: decodeID} }
+
+// Header binds the request header name as a gx.ID.
+func Header(name string) Spec { return Spec{source: SourceHeader, name: name, decode: decodeID} }
+
+// TypedParam binds the path param name as a gx.TypedID[K], checking its kind.
+func TypedParam[K gx.IDKind](name string) Spec {
+	return Spec{source: SourceParam, name: name, decode: decodeTyped[K]}
+}
+
+func TypedQuery[K gx.IDKind](name string) Spec {
+	return Spec{source: SourceQuery, name: name, decode: decodeTyped[K]}
+}
+
+func TypedHeader[K gx.IDKind](name string) Spec {
+	return Spec{source: SourceHeader, name: name, decode: decodeTyped[K]}
+}
+
+// As stores the value under local instead of the spec's name.
+func (s Spec) As(local string) Spec { s.local = local; return s }
+
+// Optional skips the spec when the value is absent instead of failing.
+func (s Spec) Optional() Spec { s.optional = true; return s }
+
+func (s Spec) localKey() string {
+	if s.local != "" {
+		return s.local
+	}
+	return s.name
+}
+
+func (s Spec) raw(c *fiber.Ctx) string {
+	switch s.source {
+	case SourceQuery:
+		return c.Query(s.name)
+	case SourceHeader:
+		return c.Get(s.name)
+	default:
+		return c.Params(s.name)
+	}
+}
+
+func (s Spec) bind(c *fiber.Ctx) (any, *Error) {
+	raw := strings.TrimSpace(s.raw(c))
+	if raw == "" {
+		if s.optional {
+			return nil, nil
+		}
+		return nil, &Error{Status: fiber.StatusBadRequest, Code: "missing_id", Source: s.source, Name: s.name, Message: "id is required"}
+	}
+	v, err := s.decode(c, raw)
+	if err != nil {
+		return nil, classify(s, err)
+	}
+	return v, nil
+}
+
+func classify(s Spec, err error) *Error {
+	e := &Error{Source: s.source, Name: s.name, Err: err}
+	switch {
+	case errors.Is(err, idcodec.ErrMACVerification), errors.Is(err, idcodec.ErrKindMismatch):
+		e.Status, e.Code, e.Message = fiber.StatusNotFound, "not_found", "no such id"
+	case errors.Is(err, idcodec.ErrInvalidLength), errors.Is(err, idcodec.ErrInvalidBase62Char),
+		errors.Is(err, idcodec.ErrVersionMismatch), errors.Is(err, idcodec.ErrOverflow),
+		errors.Is(err, idcodec.ErrChecksum):
+		e.Status, e.Code, e.Message = fiber.StatusBadRequest, "invalid_id", "malformed id"
+	default:
+		// codec not configured and the like: a server problem, not the client's
+		e.Status, e.Code, e.Message = fiber.StatusInternalServerError, "id_codec_error", "cannot decode id"
+	}
+	return e
+}
+
+// IDParam is a middleware that binds the IDs described by its specs into
+// c.Locals before the handler runs.
+type IDParam struct {
+	specs   []Spec
+	log     *zap.Logger
+	onError func(c *fiber.Ctx, err *Error) error
+}
+
+func New(specs []Spec, option ...Option) *IDParam {
+	p := &IDParam{
+		specs:   specs,
+		log:     zap.NewNop(),
+		onError: respond,
+	}
+	for _, opt := range option {
+		opt(p)
+	}
+	return p
+}
+
+func (p *IDParam) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, s := range p.specs {
+			v, e := s.bind(c)
+			if e != nil {
+				if e.Status >= fiber.StatusInternalServerError {
+					p.log.Error("failed to decode id", zap.String("source", string(e.Source)), zap.String("name", e.Name), zap.Error(e.Err))
+				}
+				return p.onError(c, e)
+			}
+			if v != nil {
+				c.Locals(s.localKey(), v)
+			}
+		}
+		return c.Next()
+	}
+}
+
+func respond(c *fiber.Ctx, e *Error) error {
+	return c.Status(e.Status).JSON(fiber.Map{"error": e})
+}
+
+// Respond writes the structured error body for an error returned by the
+// helpers below; other errors are returned unchanged.
+func Respond(c *fiber.Ctx, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return respond(c, e)
+	}
+	return err
+}
+
+// ParamID decodes the path param name in a handler without the middleware.
+func ParamID(c *fiber.Ctx, name string) (gx.ID, error) {
+	return lookup[gx.ID](c, Param(name))
+}
+
+func QueryID(c *fiber.Ctx, name string) (gx.ID, error) {
+	return lookup[gx.ID](c, Query(name))
+}
+
+func HeaderID(c *fiber.Ctx, name string) (gx.ID, error) {
+	return lookup[gx.ID](c, Header(name))
+}
+
+func TypedParamID[K gx.IDKind](c *fiber.Ctx, name string) (gx.TypedID[K], error) {
+	return lookup[gx.TypedID[K]](c, TypedParam[K](name))
+}
+
+func TypedQueryID[K gx.IDKind](c *fiber.Ctx, name string) (gx.TypedID[K], error) {
+	return lookup[gx.TypedID[K]](c, TypedQuery[K](name))
+}
+
+func TypedHeaderID[K gx.IDKind](c *fiber.Ctx, name string) (gx.TypedID[K], error) {
+	return lookup[gx.TypedID[K]](c, TypedHeader[K](name))
+}
+
+func lookup[T any](c *fiber.Ctx, s Spec) (T, error) {
+	var zero T
+	v, e := s.bind(c)
+	if e != nil {
+		return zero, e
+	}
+	if v == nil {
+		return zero, nil
+	}
+	return v.(T), nil
+}
+
+// Local returns the gx.ID the middleware stored under name.
+func Local(c *fiber.Ctx, name string) (gx.ID, bool) {
+	v, ok := c.Locals(name).(gx.ID)
+	return v, ok
+}
+
+// LocalTyped returns the gx.TypedID[K] the middleware stored under name.
+func LocalTyped[K gx.IDKind](c *fiber.Ctx, name string) (gx.TypedID[K], bool) {
+	v, ok := c.Locals(name).(gx.TypedID[K])
+	return v, ok
+}
@@ -0,0 +1,155 @@
+package idparam
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bronystylecrazy/gx"
+	"github.com/bronystylecrazy/gx/idcodec"
+	"github.com/gofiber/fiber/v2"
+)
+
+type userKind struct{}
+
+func (userKind) Kind() byte { return 'U' }
+
+type orderKind struct{}
+
+func (orderKind) Kind() byte { return 'O' }
+
+func init() {
+	gx.RegisterIDKind[userKind]()
+	gx.RegisterIDKind[orderKind]()
+}
+
+func testApp(t *testing.T) *fiber.App {
+	t.Helper()
+	gx.SetDefaultCodec(idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("idparam"), MacLen: 6, AllowWeakSecret: true}))
+	app := fiber.New()
+	bind := New([]Spec{TypedParam[userKind]("id"), Query("ref").Optional(), Header("X-Org").As("org").Optional()})
+	app.Get("/users/:id", bind.Handler(), func(c *fiber.Ctx) error {
+		id, _ := LocalTyped[userKind](c, "id")
+		ref, _ := Local(c, "ref")
+		org, _ := Local(c, "org")
+		return c.JSON(fiber.Map{"id": id.Uint64(), "ref": ref.Uint64(), "org": org.Uint64()})
+	})
+	app.Get("/plain/:id", func(c *fiber.Ctx) error {
+		id, err := ParamID(c, "id")
+		if err != nil {
+			return Respond(c, err)
+		}
+		return c.SendString(id.String())
+	})
+	app.Get("/orders", func(c *fiber.Ctx) error {
+		order, err := TypedQueryID[orderKind](c, "order")
+		if err != nil {
+			return Respond(c, err)
+		}
+		user, err := TypedHeaderID[userKind](c, "X-User")
+		if err != nil {
+			return Respond(c, err)
+		}
+		return c.JSON(fiber.Map{"order": order.Uint64(), "user": user.Uint64()})
+	})
+	return app
+}
+
+func do(t *testing.T, app *fiber.App, path string, hdr map[string]string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	var body map[string]any
+	_ = json.Unmarshal(b, &body)
+	return resp.StatusCode, body
+}
+
+func TestMiddlewareBindsIDs(t *testing.T) {
+	app := testApp(t)
+	user := gx.TypedID[userKind](7).String()
+	ref := gx.ID(8).String()
+	org := gx.ID(9).String()
+
+	status, body := do(t, app, "/users/"+user+"?ref="+ref, map[string]string{"X-Org": org})
+	if status != 200 || body["id"] != 7.0 || body["ref"] != 8.0 || body["org"] != 9.0 {
+		t.Fatalf("got %d %v", status, body)
+	}
+	if status, _ := do(t, app, "/users/"+user, nil); status != 200 {
+		t.Fatalf("optional specs must not be required: %d", status)
+	}
+}
+
+func TestTypedHelpers(t *testing.T) {
+	app := testApp(t)
+	order := gx.TypedID[orderKind](5).String()
+	user := gx.TypedID[userKind](6).String()
+
+	status, body := do(t, app, "/orders?order="+order, map[string]string{"X-User": user})
+	if status != 200 || body["order"] != 5.0 || body["user"] != 6.0 {
+		t.Fatalf("got %d %v", status, body)
+	}
+	// kinds swapped between query and header
+	status, body = do(t, app, "/orders?order="+user, map[string]string{"X-User": order})
+	if e, _ := body["error"].(map[string]any); status != 404 || e["source"] != "query" {
+		t.Fatalf("query kind mismatch: got %d %v", status, body)
+	}
+	status, body = do(t, app, "/orders?order="+order, map[string]string{"X-User": order})
+	if e, _ := body["error"].(map[string]any); status != 404 || e["source"] != "header" {
+		t.Fatalf("header kind mismatch: got %d %v", status, body)
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	app := testApp(t)
+	cases := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/users/short", 400, "invalid_id"},
+		{"/users/" + gx.TypedID[orderKind](7).String(), 404, "not_found"},
+		{"/users/" + gx.TypedID[userKind](7).String() + "?ref=bogus", 400, "invalid_id"},
+		{"/plain/nope", 400, "invalid_id"},
+	}
+	for _, tc := range cases {
+		status, body := do(t, app, tc.path, nil)
+		e, _ := body["error"].(map[string]any)
+		if status != tc.status || e["code"] != tc.code {
+			t.Fatalf("%s: got %d %v", tc.path, status, body)
+		}
+	}
+}
+
+func TestMiddlewareUsesContextCodec(t *testing.T) {
+	gx.SetDefaultCodec(idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("idparam"), MacLen: 6, AllowWeakSecret: true}))
+	tenant := idcodec.MustNewCodecFromSecret(idcodec.Config{Secret: []byte("tenant-a"), MacLen: 6, AllowWeakSecret: true})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(gx.WithIDCodec(c.UserContext(), tenant))
+		return c.Next()
+	})
+	bind := New([]Spec{TypedParam[userKind]("id"), Query("ref").Optional()})
+	app.Get("/users/:id", bind.Handler(), func(c *fiber.Ctx) error {
+		id, _ := LocalTyped[userKind](c, "id")
+		ref, _ := Local(c, "ref")
+		return c.JSON(fiber.Map{"id": id.Uint64(), "ref": ref.Uint64()})
+	})
+
+	kind := userKind{}.Kind()
+	user := tenant.EncodeUint64WithKind(7, &kind)
+	status, body := do(t, app, "/users/"+user+"?ref="+tenant.EncodeUint64(8), nil)
+	if status != 200 || body["id"] != 7.0 || body["ref"] != 8.0 {
+		t.Fatalf("got %d %v", status, body)
+	}
+	if status, _ := do(t, app, "/users/"+gx.TypedID[userKind](7).String(), nil); status != 404 {
+		t.Fatalf("default-codec token must not decode for the tenant: %d", status)
+	}
+}
@@ -0,0 +1,21 @@
+package idparam
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Option = func(*IDParam)
+
+var WithLogger = func(logger *zap.Logger) Option {
+	return func(p *IDParam) {
+		p.log = logger
+	}
+}
+
+// WithErrorHandler replaces the default JSON error response.
+var WithErrorHandler = func(h func(c *fiber.Ctx, err *Error) error) Option {
+	return func(p *IDParam) {
+		p.onError = h
+	}
+}